@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"math"
+)
+
+// BlockmapCellSize is the width and height of a blockmap cell in map units.
+const BlockmapCellSize = 128
+
+const blockmapHeaderSize int32 = 8
+
+// Blockmap see: https://doomwiki.org/wiki/Blockmap
+//
+// The blockmap divides the map into a grid of cells, each listing the linedefs that pass through it, so that
+// collision detection only needs to test the lines close to a moving object.
+type Blockmap struct {
+	OriginX int16
+	OriginY int16
+	Columns int16
+	Rows    int16
+	cells   [][]int16
+}
+
+func readBlockmap(directory Directory, data []byte) (Blockmap, error) {
+	if int32(len(data)) < blockmapHeaderSize {
+		return Blockmap{}, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	}
+	blockmap := Blockmap{
+		OriginX: readInt[int16](data[0:2]),
+		OriginY: readInt[int16](data[2:4]),
+		Columns: readInt[int16](data[4:6]),
+		Rows:    readInt[int16](data[6:8]),
+	}
+	numCells := int(blockmap.Columns) * int(blockmap.Rows)
+	if blockmap.Columns < 0 || blockmap.Rows < 0 || len(data) < int(blockmapHeaderSize)+2*numCells {
+		return Blockmap{}, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	}
+
+	numWords := len(data) / 2
+	blockmap.cells = make([][]int16, numCells)
+	for cell := 0; cell < numCells; cell++ {
+		index := int(blockmapHeaderSize) + 2*cell
+		// offsets are given in 16-bit words from the start of the lump, large maps rely on them being unsigned
+		word := int(uint16(readInt[int16](data[index : index+2])))
+
+		// lists are terminated by -1. Most blockmap builders start them with a 0 entry, which some leave out; like the
+		// original engine every entry is kept, so a leading 0 adds linedef 0 but a list really starting with it is
+		// never cut short
+		var linedefs []int16
+		for ; ; word++ {
+			if word >= numWords {
+				return Blockmap{}, &LumpError{Lump: directory.name, Offset: directory.filepos + int32(index), Err: ErrIndexOutOfRange}
+			}
+			linedef := readInt[int16](data[2*word : 2*word+2])
+			if linedef == -1 {
+				break
+			}
+			linedefs = append(linedefs, linedef)
+		}
+		blockmap.cells[cell] = linedefs
+	}
+	return blockmap, nil
+}
+
+// Linedefs returns the indexes of the linedefs passing through the cell at the given column and row, or nil if the
+// cell lies outside the grid. Most lists also hold linedef 0 from the leading 0 entry written by the blockmap builder.
+func (b Blockmap) Linedefs(column int, row int) []int16 {
+	if column < 0 || row < 0 || column >= int(b.Columns) || row >= int(b.Rows) {
+		return nil
+	}
+	return b.cells[row*int(b.Columns)+column]
+}
+
+// CellForPosition returns the column and row of the cell containing the given map position.
+func (b Blockmap) CellForPosition(x float64, y float64) (column int, row int, ok bool) {
+	column = int(math.Floor((x - float64(b.OriginX)) / BlockmapCellSize))
+	row = int(math.Floor((y - float64(b.OriginY)) / BlockmapCellSize))
+	ok = column >= 0 && row >= 0 && column < int(b.Columns) && row < int(b.Rows)
+	return
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestReadBlockmap(t *testing.T) {
+	// two cells, the first list with the leading 0, the second without it as written by some builders, starting with
+	// linedef 0
+	data := le(int16(-64), int16(32), int16(2), int16(1), uint16(6), uint16(10), int16(0), int16(5), int16(7),
+		int16(-1), int16(0), int16(3), int16(-1))
+	blockmap, err := readBlockmap(Directory{name: BlockmapLump}, data)
+	if err != nil {
+		t.Fatalf("readBlockmap: %v", err)
+	}
+	if blockmap.OriginX != -64 || blockmap.OriginY != 32 || blockmap.Columns != 2 || blockmap.Rows != 1 {
+		t.Errorf("header %+v", blockmap)
+	}
+	if got := blockmap.Linedefs(0, 0); !slices.Equal(got, []int16{0, 5, 7}) {
+		t.Errorf("cell (0, 0) = %v, want [0 5 7]", got)
+	}
+	if got := blockmap.Linedefs(1, 0); !slices.Equal(got, []int16{0, 3}) {
+		t.Errorf("cell (1, 0) = %v, want [0 3]", got)
+	}
+	if got := blockmap.Linedefs(2, 0); got != nil {
+		t.Errorf("cell outside the grid = %v", got)
+	}
+	if column, row, ok := blockmap.CellForPosition(100, 40); column != 1 || row != 0 || !ok {
+		t.Errorf("CellForPosition = %d, %d, %v", column, row, ok)
+	}
+}
+
+func TestReadBlockmapErrors(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want error
+	}{
+		"short header":       {le(int16(0), int16(0)), ErrShortLump},
+		"short offsets":      {le(int16(0), int16(0), int16(2), int16(2), uint16(8)), ErrShortLump},
+		"unterminated list":  {le(int16(0), int16(0), int16(1), int16(1), uint16(5), int16(0), int16(1)), ErrIndexOutOfRange},
+		"offset out of lump": {le(int16(0), int16(0), int16(1), int16(1), uint16(100)), ErrIndexOutOfRange},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readBlockmap(Directory{name: BlockmapLump}, test.data); !errors.Is(err, test.want) {
+				t.Errorf("readBlockmap error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestRejectCanSectorSee(t *testing.T) {
+	// three sectors, sector 0 cannot see sector 2 and sector 2 cannot see sector 1
+	reject := readReject([]byte{0b1000_0100}, 3)
+	tests := []struct {
+		a, b int
+		want bool
+	}{
+		{0, 0, true}, {0, 2, false}, {2, 1, false}, {1, 2, true},
+		{2, 2, true}, // bit 8 lies past the truncated lump
+		{0, 3, true}, // sector out of range
+	}
+	for _, test := range tests {
+		if got := reject.CanSectorSee(test.a, test.b); got != test.want {
+			t.Errorf("CanSectorSee(%d, %d) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package engine
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
 	"math"
 )
 
@@ -18,6 +17,64 @@ type Node struct {
 	leftChild        int16
 }
 
+// subSectorFlag marks node children that refer to a subsector instead of another node
+const subSectorFlag = 0x8000
+
+// pointOnSide returns 0 if the given map position lies on the front (right) side of the node's partition line and 1
+// if it lies on the back (left) side.
+func (n Node) pointOnSide(x float64, y float64) int {
+	dx := x - float64(n.partitionLineX)
+	dy := y - float64(n.partitionLineY)
+	if dy*float64(n.dxPartitionLineX) < float64(n.dyPartitionLineY)*dx {
+		return 0
+	}
+	return 1
+}
+
+// child returns the child on the given side as returned by pointOnSide.
+func (n Node) child(side int) int16 {
+	if side == 0 {
+		return n.rightChild
+	}
+	return n.leftChild
+}
+
+// boundingBox returns the bounding box of the child on the given side.
+func (n Node) boundingBox(side int) int64 {
+	if side == 0 {
+		return n.rightBoundingBox
+	}
+	return n.leftBoundingBox
+}
+
+// rootNode returns the node to start BSP traversals with. Maps consisting of a single subsector have no nodes.
+func (m *Map) rootNode() int16 {
+	if len(m.Nodes) == 0 {
+		return -1 // subsector 0
+	}
+	return int16(len(m.Nodes) - 1)
+}
+
+// PointInSubSector returns the index of the subsector containing the given map position.
+func (m *Map) PointInSubSector(x float64, y float64) int {
+	nodeId := m.rootNode()
+	for nodeId >= 0 {
+		nodeId = m.Nodes[nodeId].child(m.Nodes[nodeId].pointOnSide(x, y))
+	}
+	return subSectorIndex(nodeId)
+}
+
+// subSectorIndex returns the subsector a node child refers to.
+func subSectorIndex(nodeId int16) int {
+	if nodeId == -1 {
+		return 0 // root of a map without nodes
+	}
+	return int(uint16(nodeId) &^ subSectorFlag)
+}
+
+// NoSector marks the missing back sector of a seg on a one-sided linedef.
+const NoSector int16 = -1
+
 // Sector see: https://doom.fandom.com/wiki/Sector
 type Sector struct {
 	floorHeight          int16
@@ -29,10 +86,44 @@ type Sector struct {
 	tagNumber            int16
 }
 
+func (s Sector) FloorHeight() int16 {
+	return s.floorHeight
+}
+
+func (s Sector) CeilingHeight() int16 {
+	return s.ceilingHeight
+}
+
+func (s Sector) FloorTexture() string {
+	return s.nameOfFloorTexture
+}
+
+func (s Sector) CeilingTexture() string {
+	return s.nameOfCeilingTexture
+}
+
+func (s Sector) LightLevel() int16 {
+	return s.lightLevel
+}
+
+func (s Sector) Type() int16 {
+	return s.sectorType
+}
+
+func (s Sector) Tag() int16 {
+	return s.tagNumber
+}
+
 // SubSector see: https://doom.fandom.com/wiki/Subsector
 type SubSector struct {
 	segCount       int16
 	firstSegNumber int16
+	sector         int16
+}
+
+// Sector returns the index of the sector the subsector lies in.
+func (s SubSector) Sector() int16 {
+	return s.sector
 }
 
 // Seg see: https://doom.fandom.com/wiki/Seg
@@ -43,16 +134,28 @@ type Seg struct {
 	lineDefNumber        int16
 	direction            int16
 	offset               int16
+	frontSector          int16
+	backSector           int16
+	sideDef              int16 // sidedef on the side of the seg facing the viewer
+}
+
+// FrontSector returns the index of the sector on the side of the seg facing the viewer.
+func (s Seg) FrontSector() int16 {
+	return s.frontSector
+}
+
+// BackSector returns the index of the sector behind the seg, or NoSector if the seg belongs to a one-sided linedef.
+func (s Seg) BackSector() int16 {
+	return s.backSector
 }
 
 var depthColor uint8 = 0
 
-func Traverse(nodeId int16, currentMap *Map, x float32, y float32, screen *ebiten.Image) {
+func Traverse(nodeId int16, currentMap *Map, x float32, y float32, screen *Framebuffer) {
 	if nodeId < 0 {
-		subSectorId := uint16(nodeId) - uint16(0x8000)
-		subSector := currentMap.SubSectors[subSectorId]
+		subSector := currentMap.SubSectors[subSectorIndex(nodeId)]
 		//if !DrawBoundingBoxesInMap {
-		DrawSubSector(screen, subSector, currentMap.Segs, currentMap.Vertexes, depthColor)
+		DrawSubSector(screen, subSector, currentMap.Segs, currentMap.Vertexes, mapSubSectorColor+depthColor)
 		//	depthColor++
 		//time.Sleep(500 * time.Microsecond)
 		//}
@@ -75,7 +178,10 @@ func Traverse(nodeId int16, currentMap *Map, x float32, y float32, screen *ebite
 }
 
 func collidesWithBoundingBox(data int64) bool {
-	boundingBox := ConvertToBoundingBox(data)
+	top, bottom, left, right := decodeBoundingBox(data)
+	boundingBox := BoundingBox{right: float32(right), left: float32(left), bottom: float32(bottom), top: float32(top)}
+	playerX := float32(PlayerX)
+	playerY := float32(PlayerY)
 
 	// bounding box vertices
 	a := Vec2{boundingBox.left, boundingBox.bottom}
@@ -83,42 +189,38 @@ func collidesWithBoundingBox(data int64) bool {
 	c := Vec2{boundingBox.right, boundingBox.top}
 	d := Vec2{boundingBox.right, boundingBox.bottom}
 
-	var boundingBoxSides [4]Vec2
+	// sides of the bounding box facing the player, each given as a pair of vertices
+	var boundingBoxSides [][2]Vec2
 
-	if PlayerOffsetX < boundingBox.left {
-		if PlayerOffsetY > boundingBox.top {
-			boundingBoxSides = [4]Vec2{b, a, c, b}
-		} else if PlayerOffsetY < boundingBox.bottom {
-			boundingBoxSides = [4]Vec2{b, a, a, d}
+	if playerX < boundingBox.left {
+		if playerY > boundingBox.top {
+			boundingBoxSides = [][2]Vec2{{b, a}, {c, b}}
+		} else if playerY < boundingBox.bottom {
+			boundingBoxSides = [][2]Vec2{{b, a}, {a, d}}
 		} else {
-			boundingBoxSides = [4]Vec2{b, a, {}, {}}
+			boundingBoxSides = [][2]Vec2{{b, a}}
 		}
-	} else if PlayerOffsetX > boundingBox.right {
-		if PlayerOffsetY > boundingBox.top {
-			boundingBoxSides = [4]Vec2{c, b, d, c}
-		} else if PlayerOffsetY > boundingBox.bottom {
-			boundingBoxSides = [4]Vec2{a, d, d, c}
+	} else if playerX > boundingBox.right {
+		if playerY > boundingBox.top {
+			boundingBoxSides = [][2]Vec2{{c, b}, {d, c}}
+		} else if playerY < boundingBox.bottom {
+			boundingBoxSides = [][2]Vec2{{a, d}, {d, c}}
 		} else {
-			boundingBoxSides = [4]Vec2{d, c, {}, {}}
+			boundingBoxSides = [][2]Vec2{{d, c}}
 		}
 	} else {
-		if PlayerOffsetY > boundingBox.top {
-			boundingBoxSides = [4]Vec2{c, b, {}, {}}
-		} else if PlayerOffsetY < boundingBox.bottom {
-			boundingBoxSides = [4]Vec2{a, d, {}, {}}
+		if playerY > boundingBox.top {
+			boundingBoxSides = [][2]Vec2{{c, b}}
+		} else if playerY < boundingBox.bottom {
+			boundingBoxSides = [][2]Vec2{{a, d}}
 		} else {
-			panic("Could not determine bounding box collision")
+			return true // player is inside the bounding box
 		}
 	}
 
-	for i := 0; i < 4; i += 2 {
-
-		if boundingBoxSides[i] == (Vec2{}) {
-			continue
-		}
-
-		angle1 := angleFromPlayer(boundingBoxSides[i])
-		angle2 := angleFromPlayer(boundingBoxSides[i+1])
+	for _, side := range boundingBoxSides {
+		angle1 := angleFromPlayer(side[0])
+		angle2 := angleFromPlayer(side[1])
 
 		span := normalizeAngle(int32(angle1 - angle2))
 
@@ -137,8 +239,8 @@ func collidesWithBoundingBox(data int64) bool {
 
 // angleFromPlayer determines the angle between the players position and the given vertex
 func angleFromPlayer(vertex Vec2) float64 {
-	deltaX := float64(vertex.x - PlayerOffsetX)
-	deltaY := float64(vertex.y - PlayerOffsetY)
+	deltaX := float64(vertex.x) - PlayerX
+	deltaY := float64(vertex.y) - PlayerY
 	return RadToDeg(math.Atan2(deltaY, deltaX))
 }
 
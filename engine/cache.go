@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"container/list"
+)
+
+// DefaultMapCacheSize is the number of parsed maps kept by a MapCache unless specified otherwise.
+const DefaultMapCacheSize = 4
+
+// MapCache parses maps when they are first entered and keeps the most recently used ones, so that large megawads are
+// not parsed up front and held in memory as a whole.
+type MapCache struct {
+	wad      *Wad
+	capacity int
+	maps     map[string]*list.Element
+	order    *list.List // of *Map, most recently used first
+}
+
+func NewMapCache(wad *Wad, capacity int) *MapCache {
+	return &MapCache{
+		wad:      wad,
+		capacity: max(capacity, 1),
+		maps:     make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the map with the given name, parsing it if it is not cached. The least recently used map is evicted
+// once the cache is full.
+func (c *MapCache) Get(mapName string) (*Map, error) {
+	if element, ok := c.maps[mapName]; ok {
+		c.order.MoveToFront(element)
+		return element.Value.(*Map), nil
+	}
+
+	currentMap, err := c.wad.ReadMapData(mapName)
+	if err != nil {
+		return nil, err
+	}
+	c.maps[mapName] = c.order.PushFront(&currentMap)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		delete(c.maps, oldest.Value.(*Map).Name)
+		c.order.Remove(oldest)
+	}
+	return &currentMap, nil
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMapCacheGet(t *testing.T) {
+	var lumps []testLump
+	for _, name := range []string{"E1M1", "E1M2", "E1M3"} {
+		lumps = append(lumps, testMapLumps(name)...)
+	}
+	cache := NewMapCache(loadWad(t, "IWAD", lumps), 2)
+	get := func(name string) *Map {
+		t.Helper()
+		m, err := cache.Get(name)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", name, err)
+		}
+		if m.Name != name {
+			t.Fatalf("Get(%s) returned %s", name, m.Name)
+		}
+		return m
+	}
+
+	if len(cache.maps) != 0 {
+		t.Fatalf("%d maps parsed before first use", len(cache.maps))
+	}
+	e1m1 := get("E1M1")
+	if get("E1M1") != e1m1 {
+		t.Errorf("E1M1 parsed again instead of reused")
+	}
+	e1m2 := get("E1M2")
+	get("E1M1") // E1M2 is now the least recently used
+	get("E1M3")
+	if len(cache.maps) != 2 || cache.order.Len() != 2 {
+		t.Errorf("cache holds %d maps, want 2", len(cache.maps))
+	}
+	if _, ok := cache.maps["E1M2"]; ok {
+		t.Errorf("E1M2 not evicted")
+	}
+	if get("E1M1") != e1m1 {
+		t.Errorf("E1M1 evicted instead of E1M2")
+	}
+	if get("E1M2") == e1m2 {
+		t.Errorf("evicted E1M2 returned without parsing it again")
+	}
+	if _, ok := cache.maps["E1M3"]; ok {
+		t.Errorf("E1M3 not evicted")
+	}
+
+	if _, err := cache.Get("E1M9"); !errors.Is(err, ErrLumpNotFound) {
+		t.Errorf("Get(E1M9) error = %v, want %v", err, ErrLumpNotFound)
+	}
+	if len(cache.maps) != 2 {
+		t.Errorf("failed load changed the cache to %d maps", len(cache.maps))
+	}
+}
+
+func TestMapCacheMinimumCapacity(t *testing.T) {
+	lumps := append(testMapLumps("E1M1"), testMapLumps("E1M2")...)
+	cache := NewMapCache(loadWad(t, "IWAD", lumps), 0)
+	for _, name := range []string{"E1M1", "E1M2"} {
+		if _, err := cache.Get(name); err != nil {
+			t.Fatalf("Get(%s): %v", name, err)
+		}
+	}
+	if len(cache.maps) != 1 {
+		t.Errorf("cache of capacity 0 holds %d maps, want 1", len(cache.maps))
+	}
+}
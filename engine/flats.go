@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"image"
+	"strings"
+)
+
+// Flats see: https://doomwiki.org/wiki/Flat
+const (
+	FlatStartMarker = "F_START"
+	FlatEndMarker   = "F_END"
+	SkyFlatName     = "F_SKY1"
+
+	FlatSize      = 64
+	FlatBlockSize = FlatSize * FlatSize
+
+	// FlatAnimationSpeed is the number of tics each frame of an animated flat is shown
+	FlatAnimationSpeed = 8
+
+	// untexturedFlatColor fills flats whose lump is too short to hold the pixels
+	untexturedFlatColor byte = 88
+)
+
+// animatedFlats lists the first and last frame of the flat animations of the original engine. The frames are the
+// flats between them in WAD order.
+var animatedFlats = [][2]string{
+	{"NUKAGE1", "NUKAGE3"},
+	{"FWATER1", "FWATER4"},
+	{"SWATER1", "SWATER4"},
+	{"LAVA1", "LAVA4"},
+	{"BLOOD1", "BLOOD3"},
+
+	// Doom II
+	{"RROCK05", "RROCK08"},
+	{"SLIME01", "SLIME04"},
+	{"SLIME05", "SLIME08"},
+	{"SLIME09", "SLIME12"},
+}
+
+// Flat is a 64x64 floor or ceiling texture.
+type Flat struct {
+	Name   string
+	Pixels []byte // row by row
+}
+
+// Flats is the table of all flats, looked up by the names used in sectors.
+type Flats struct {
+	flats       []*Flat
+	byName      map[string]int
+	translation []int // flat shown in place of each flat, advanced by Animate
+	animations  []flatAnimation
+}
+
+type flatAnimation struct {
+	first     int
+	numFrames int
+}
+
+// ReadFlats builds the flat table from the lumps between F_START and F_END. PWAD flats have been merged into this
+// namespace; a flat replacing one of the same name keeps its position so animation sequences stay intact.
+func (w *Wad) ReadFlats() (*Flats, error) {
+	start, ok := w.ReadLumpIndexForName(FlatStartMarker)
+	if !ok {
+		return nil, &LumpError{Lump: FlatStartMarker, Offset: 0, Err: ErrLumpNotFound}
+	}
+	end, ok := w.FindLumpInRange(FlatEndMarker, start, w.NumLumps())
+	if !ok {
+		return nil, &LumpError{Lump: FlatEndMarker, Offset: 0, Err: ErrLumpNotFound}
+	}
+
+	flats := &Flats{byName: make(map[string]int)}
+	for i := start + 1; i < end; i++ {
+		directory := w.ReadDirectoryForLumpIndex(i)
+		if directory.size == 0 {
+			continue // sub-markers like F1_START
+		}
+		flat := &Flat{Name: directory.name}
+		if directory.size < FlatBlockSize {
+			// a broken flat is drawn untextured rather than losing all flats
+			flat.Pixels = make([]byte, FlatBlockSize)
+			for j := range flat.Pixels {
+				flat.Pixels[j] = untexturedFlatColor
+			}
+		} else {
+			data, err := w.ReadLumpData(directory)
+			if err != nil {
+				return nil, err
+			}
+			flat.Pixels = data[:FlatBlockSize]
+		}
+		if index, ok := flats.byName[flat.Name]; ok {
+			flats.flats[index] = flat
+			continue
+		}
+		flats.byName[flat.Name] = len(flats.flats)
+		flats.flats = append(flats.flats, flat)
+	}
+
+	flats.translation = make([]int, len(flats.flats))
+	for i := range flats.translation {
+		flats.translation[i] = i
+	}
+	for _, animation := range animatedFlats {
+		first, ok := flats.byName[animation[0]]
+		if !ok {
+			continue
+		}
+		last, ok := flats.byName[animation[1]]
+		if !ok || last <= first {
+			continue
+		}
+		flats.animations = append(flats.animations, flatAnimation{first: first, numFrames: last - first + 1})
+	}
+	return flats, nil
+}
+
+// Animate advances the animated flats to the frames shown at the given game tic.
+func (f *Flats) Animate(tic int) {
+	for _, animation := range f.animations {
+		for i := 0; i < animation.numFrames; i++ {
+			f.translation[animation.first+i] = animation.first + (tic/FlatAnimationSpeed+i)%animation.numFrames
+		}
+	}
+}
+
+// Index returns the position of the named flat in the table.
+func (f *Flats) Index(name string) (int, bool) {
+	index, ok := f.byName[strings.ToUpper(name)]
+	return index, ok
+}
+
+// Lookup returns the flat currently shown for the given name, taking animations into account.
+func (f *Flats) Lookup(name string) (*Flat, bool) {
+	index, ok := f.Index(name)
+	if !ok {
+		return nil, false
+	}
+	return f.Flat(index), true
+}
+
+// Flat returns the flat currently shown for the flat at the given position in the table.
+func (f *Flats) Flat(index int) *Flat {
+	return f.flats[f.translation[index]]
+}
+
+// Len returns the number of flats in the table.
+func (f *Flats) Len() int {
+	return len(f.flats)
+}
+
+// Image returns the flat as a 64x64 paletted image.
+func (f *Flat) Image(palette *Palette) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, FlatSize, FlatSize), palette.ColorPalette())
+	copy(img.Pix, f.Pixels)
+	return img
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+// testFlat returns the pixels of a flat of a single color.
+func testFlat(color byte) []byte {
+	pixels := make([]byte, FlatBlockSize)
+	for i := range pixels {
+		pixels[i] = color
+	}
+	return pixels
+}
+
+func TestReadFlats(t *testing.T) {
+	wad := loadWad(t, "IWAD", []testLump{
+		{name: FlatStartMarker},
+		{name: "F1_START"},
+		{name: "NUKAGE1", data: testFlat(1)},
+		{name: "NUKAGE2", data: testFlat(9)},
+		{name: "NUKAGE3", data: testFlat(3)},
+		{name: "F1_END"},
+		{name: "FLOOR0_1", data: testFlat(40)},
+		{name: "NUKAGE2", data: testFlat(2)}, // replacement merged from a PWAD
+		{name: FlatEndMarker},
+	})
+	flats, err := wad.ReadFlats()
+	if err != nil {
+		t.Fatalf("ReadFlats: %v", err)
+	}
+	if flats.Len() != 4 {
+		t.Fatalf("got %d flats, want 4", flats.Len())
+	}
+	if index, ok := flats.Index("nukage2"); !ok || index != 1 || flats.Flat(index).Pixels[0] != 2 {
+		t.Errorf("NUKAGE2 not replaced in place")
+	}
+
+	// the animation cycles through all frames, FLOOR0_1 stays
+	for i, want := range []byte{1, 2, 3, 1} {
+		tic := i * FlatAnimationSpeed
+		flats.Animate(tic)
+		if got, _ := flats.Lookup("NUKAGE1"); got.Pixels[0] != want {
+			t.Errorf("tic %d: NUKAGE1 shows color %d, want %d", tic, got.Pixels[0], want)
+		}
+		if got, _ := flats.Lookup("FLOOR0_1"); got.Pixels[0] != 40 {
+			t.Errorf("tic %d: FLOOR0_1 shows color %d", tic, got.Pixels[0])
+		}
+	}
+}
+
+func TestReadFlatsShortFlat(t *testing.T) {
+	wad := loadWad(t, "IWAD", []testLump{
+		{name: FlatStartMarker},
+		{name: "NUKAGE1", data: testFlat(1)},
+		{name: "NUKAGE2", data: testFlat(2)[:100]}, // broken
+		{name: "NUKAGE3", data: testFlat(3)},
+		{name: FlatEndMarker},
+	})
+	flats, err := wad.ReadFlats()
+	if err != nil {
+		t.Fatalf("ReadFlats: %v", err)
+	}
+	broken, ok := flats.Lookup("NUKAGE2")
+	if !ok || len(broken.Pixels) != FlatBlockSize || broken.Pixels[0] != untexturedFlatColor {
+		t.Errorf("NUKAGE2 is not drawn untextured")
+	}
+
+	// the animation cycles through all frames, including the broken one
+	for i, want := range []byte{1, untexturedFlatColor, 3, 1} {
+		tic := i * FlatAnimationSpeed
+		flats.Animate(tic)
+		if got, _ := flats.Lookup("NUKAGE1"); got.Pixels[0] != want {
+			t.Errorf("tic %d: NUKAGE1 shows color %d, want %d", tic, got.Pixels[0], want)
+		}
+	}
+}
+
+func TestReadFlatsErrors(t *testing.T) {
+	tests := map[string]struct {
+		lumps []testLump
+		want  error
+	}{
+		"no start marker": {[]testLump{{name: "FLOOR0_1", data: testFlat(1)}, {name: FlatEndMarker}}, ErrLumpNotFound},
+		"no end marker":   {[]testLump{{name: FlatStartMarker}, {name: "FLOOR0_1", data: testFlat(1)}}, ErrLumpNotFound},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadWad(t, "IWAD", test.lumps).ReadFlats(); !errors.Is(err, test.want) {
+				t.Errorf("ReadFlats error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
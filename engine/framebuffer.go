@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"image"
+	"math"
+)
+
+// Framebuffer is a palette-indexed image the renderer and the automap draw into. It does not depend on a graphics
+// context, so frames can be rendered headless and saved or compared as images.
+type Framebuffer struct {
+	Width   int
+	Height  int
+	Pixels  []byte // palette indexes, row by row
+	Palette *Palette
+}
+
+// NewFramebuffer creates a framebuffer of the given size, shown with the given palette.
+func NewFramebuffer(width int, height int, palette *Palette) *Framebuffer {
+	return &Framebuffer{
+		Width:   width,
+		Height:  height,
+		Pixels:  make([]byte, width*height),
+		Palette: palette,
+	}
+}
+
+// Clear fills the framebuffer with the given color.
+func (f *Framebuffer) Clear(color byte) {
+	for i := range f.Pixels {
+		f.Pixels[i] = color
+	}
+}
+
+// Image returns the framebuffer as a paletted image sharing its pixels.
+func (f *Framebuffer) Image() *image.Paletted {
+	return &image.Paletted{
+		Pix:     f.Pixels,
+		Stride:  f.Width,
+		Rect:    image.Rect(0, 0, f.Width, f.Height),
+		Palette: f.Palette.ColorPalette(),
+	}
+}
+
+// RGBA writes the framebuffer as 8-bit RGBA pixels to dst, which must hold 4*Width*Height bytes.
+func (f *Framebuffer) RGBA(dst []byte) {
+	for i, index := range f.Pixels {
+		c := f.Palette[index]
+		dst[4*i] = c.R
+		dst[4*i+1] = c.G
+		dst[4*i+2] = c.B
+		dst[4*i+3] = 0xff
+	}
+}
+
+// SetPixel sets the pixel at the given position, ignoring positions outside the framebuffer.
+func (f *Framebuffer) SetPixel(x int, y int, color byte) {
+	if x < 0 || y < 0 || x >= f.Width || y >= f.Height {
+		return
+	}
+	f.Pixels[y*f.Width+x] = color
+}
+
+// DrawLine draws a line between the given positions, clipped to the framebuffer.
+func (f *Framebuffer) DrawLine(x1 float32, y1 float32, x2 float32, y2 float32, color byte) {
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	steps := int(math.Ceil(max(math.Abs(dx), math.Abs(dy))))
+	if steps == 0 {
+		f.SetPixel(int(math.Floor(float64(x1))), int(math.Floor(float64(y1))), color)
+		return
+	}
+	// lines far outside the framebuffer are not worth stepping through
+	if max(x1, x2) < 0 || max(y1, y2) < 0 || min(x1, x2) >= float32(f.Width) || min(y1, y2) >= float32(f.Height) {
+		return
+	}
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		f.SetPixel(int(math.Floor(float64(x1)+dx*t)), int(math.Floor(float64(y1)+dy*t)), color)
+	}
+}
+
+// DrawRect draws the outline of a rectangle with the given top left corner and size.
+func (f *Framebuffer) DrawRect(x float32, y float32, width float32, height float32, color byte) {
+	f.DrawLine(x, y, x+width, y, color)
+	f.DrawLine(x+width, y, x+width, y+height, color)
+	f.DrawLine(x+width, y+height, x, y+height, color)
+	f.DrawLine(x, y+height, x, y, color)
+}
+
+// FillCircle draws a filled circle around the given position.
+func (f *Framebuffer) FillCircle(cx float32, cy float32, radius float32, color byte) {
+	for y := int(math.Floor(float64(cy - radius))); y <= int(math.Ceil(float64(cy+radius))); y++ {
+		for x := int(math.Floor(float64(cx - radius))); x <= int(math.Ceil(float64(cx+radius))); x++ {
+			dx, dy := float32(x)+0.5-cx, float32(y)+0.5-cy
+			if dx*dx+dy*dy <= radius*radius {
+				f.SetPixel(x, y, color)
+			}
+		}
+	}
+}
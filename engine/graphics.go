@@ -1,30 +1,37 @@
 package engine
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
-	"image/color"
 	"math"
 )
 
 const (
-	NativeResX      = 320
-	NativeResY      = 200
-	ScaleFactor     = 5
-	ScreenResX      = NativeResX * ScaleFactor
-	ScreenRexY      = NativeResY * ScaleFactor
-	ScreenCenterX   = ScreenResX / 2
-	ScreenCenterY   = ScreenRexY / 2
+	// FieldOfView is the horizontal field of view in degrees on a 4:3 display
 	FieldOfView     = 90
 	HalfFieldOfView = FieldOfView / 2
+
+	// MapUnitsPerPixel is the zoom of the automap at the original width of 320 pixels
+	MapUnitsPerPixel = 20
+)
+
+// automap colors as indexes into the Doom palette
+const (
+	mapLineColor      byte = 96  // grey
+	mapThingColor     byte = 100 // light grey
+	mapPlayerColor    byte = 176 // red
+	mapBoxColor       byte = 180 // dark red
+	mapSubSectorColor byte = 168 // bright red
+	mapFovColor       byte = 231 // yellow
 )
 
 var DrawBoundingBoxesInMap bool = false
 var offsetX float32 = 0
 var offsetY float32 = 0
+var mapUnitsPerPixel float64 = MapUnitsPerPixel
 
-func DrawMap(screen *ebiten.Image, currentMap *Map) {
-	calculateMapOffset(&currentMap.Things[0])
+// DrawMap draws the automap of the given map into the framebuffer, centred on the player.
+func DrawMap(screen *Framebuffer, currentMap *Map) {
+	screen.Clear(0)
+	calculateMapOffset(screen)
 	drawPlayer(screen)
 	drawThings(screen, &currentMap.Things)
 	drawLineDefs(screen, &currentMap.Linedefs, &currentMap.Vertexes)
@@ -33,21 +40,22 @@ func DrawMap(screen *ebiten.Image, currentMap *Map) {
 	drawFov(screen)
 }
 
-func drawFov(screen *ebiten.Image) {
-	fovLen := float64(100)
+func drawFov(screen *Framebuffer) {
+	fovLen := 20 * float64(screen.Width) / float64(DefaultResolution.Width)
 	sinAlpha := math.Sin(DegToRad(PlayerAngle - float64(HalfFieldOfView)))
 	cosAlpha := math.Cos(DegToRad(PlayerAngle - float64(HalfFieldOfView)))
 	sinBeta := math.Sin(DegToRad(PlayerAngle + float64(HalfFieldOfView)))
 	cosBeta := math.Cos(DegToRad(PlayerAngle + float64(HalfFieldOfView)))
 
-	playerX := float64(800)
-	playerY := float64(500)
-	x1 := float32(playerX + fovLen*sinAlpha)
-	y1 := float32(playerY + fovLen*cosAlpha)
-	x2 := float32(playerX + fovLen*sinBeta)
-	y2 := float32(playerY + fovLen*cosBeta)
-	vector.StrokeLine(screen, float32(playerX), float32(playerY), x1, y1, 1, color.RGBA{R: 128, G: 128, A: 128}, true)
-	vector.StrokeLine(screen, float32(playerX), float32(playerY), x2, y2, 1, color.RGBA{R: 128, G: 128, A: 128}, true)
+	// screen y-axis points down, WAD y-axis up
+	playerX := float64(screen.Width / 2)
+	playerY := float64(screen.Height / 2)
+	x1 := float32(playerX + fovLen*cosAlpha)
+	y1 := float32(playerY - fovLen*sinAlpha)
+	x2 := float32(playerX + fovLen*cosBeta)
+	y2 := float32(playerY - fovLen*sinBeta)
+	screen.DrawLine(float32(playerX), float32(playerY), x1, y1, mapFovColor)
+	screen.DrawLine(float32(playerX), float32(playerY), x2, y2, mapFovColor)
 }
 
 func DegToRad(angle float64) float64 {
@@ -58,11 +66,11 @@ func RadToDeg(angle float64) float64 {
 	return angle * (180 / math.Pi)
 }
 
-func drawBspTraversal(screen *ebiten.Image, currentMap *Map) {
-	Traverse(int16(len(currentMap.Nodes)-1), currentMap, 800, 500, screen)
+func drawBspTraversal(screen *Framebuffer, currentMap *Map) {
+	Traverse(currentMap.rootNode(), currentMap, float32(screen.Width/2), float32(screen.Height/2), screen)
 }
 
-func drawNodeBoundingBoxes(screen *ebiten.Image, nodes *[]Node) {
+func drawNodeBoundingBoxes(screen *Framebuffer, nodes *[]Node) {
 	if DrawBoundingBoxesInMap {
 		for _, node := range *nodes {
 			drawBoundingBoxes(screen, node)
@@ -70,45 +78,45 @@ func drawNodeBoundingBoxes(screen *ebiten.Image, nodes *[]Node) {
 	}
 }
 
-func drawLineDefs(screen *ebiten.Image, linedefs *[]Linedef, vertexes *[]Vertex) {
+func drawLineDefs(screen *Framebuffer, linedefs *[]Linedef, vertexes *[]Vertex) {
 	for _, linedef := range *linedefs {
 		x1 := remapX((*vertexes)[linedef.StartVertex].XPosition)
 		y1 := remapY((*vertexes)[linedef.StartVertex].YPosition)
 		x2 := remapX((*vertexes)[linedef.EndVertex].XPosition)
 		y2 := remapY((*vertexes)[linedef.EndVertex].YPosition)
-		vector.StrokeLine(screen, x1, y1, x2, y2, 2.0, color.RGBA{R: 128, G: 128, B: 128, A: 128}, true)
+		screen.DrawLine(x1, y1, x2, y2, mapLineColor)
 	}
 }
 
-func drawThings(screen *ebiten.Image, things *[]Thing) {
+func drawThings(screen *Framebuffer, things *[]Thing) {
 	for _, thing := range *things {
 		x := remapX(thing.XPosition)
 		y := remapY(thing.YPosition)
-		vector.DrawFilledCircle(screen, x, y, 2.0, color.RGBA{R: 128, G: 128, B: 128, A: 128}, true)
+		screen.FillCircle(x, y, 1, mapThingColor)
 	}
 }
 
-func drawPlayer(screen *ebiten.Image) {
-	vector.DrawFilledCircle(screen, float32(ScreenCenterX), float32(ScreenCenterY), 4.0, color.RGBA{R: 128, A: 128}, true)
+func drawPlayer(screen *Framebuffer) {
+	screen.FillCircle(float32(screen.Width/2), float32(screen.Height/2), 1.5, mapPlayerColor)
 }
 
 // DrawBoundingBoxes draws the bounding boxes (left/right) of a given node
-func drawBoundingBoxes(screen *ebiten.Image, node Node) {
-	drawBoundingBox(screen, node.leftBoundingBox, color.RGBA{R: 128, A: 128})
-	drawBoundingBox(screen, node.rightBoundingBox, color.RGBA{R: 128, A: 128})
+func drawBoundingBoxes(screen *Framebuffer, node Node) {
+	drawBoundingBox(screen, node.leftBoundingBox, mapBoxColor)
+	drawBoundingBox(screen, node.rightBoundingBox, mapBoxColor)
 }
 
-func drawBoundingBox(screen *ebiten.Image, data int64, color color.RGBA) {
+func drawBoundingBox(screen *Framebuffer, data int64, color byte) {
 	boundingBox := ConvertToBoundingBox(data)
-	vector.StrokeRect(screen, boundingBox.left, boundingBox.bottom, boundingBox.right-boundingBox.left, boundingBox.top-boundingBox.bottom, 1.0, color, true)
+	screen.DrawRect(boundingBox.left, boundingBox.bottom, boundingBox.right-boundingBox.left, boundingBox.top-boundingBox.bottom, color)
 }
 
-func DrawSubSector(screen *ebiten.Image, subSector SubSector, segs []Seg, vertexes []Vertex, depthColor uint8) {
+func DrawSubSector(screen *Framebuffer, subSector SubSector, segs []Seg, vertexes []Vertex, color byte) {
 	for i := 0; i < int(subSector.segCount); i++ {
 		seg := segs[int(subSector.firstSegNumber)+i]
 		v1 := vertexes[seg.startingVertexNumber]
 		v2 := vertexes[seg.endingVertexNumber]
-		vector.StrokeLine(screen, remapX(v1.XPosition), remapY(v1.YPosition), remapX(v2.XPosition), remapY(v2.YPosition), 3, color.RGBA{R: depthColor, A: 128}, true)
+		screen.DrawLine(remapX(v1.XPosition), remapY(v1.YPosition), remapX(v2.XPosition), remapY(v2.YPosition), color)
 	}
 }
 
@@ -126,16 +134,18 @@ func IsPlayerLeftOfSplitter(playerX float32, playerY float32, node Node, offsetX
 
 // Remap WAD X-coordinate match resolution and make more of the map visible
 func remapX(x int16) float32 {
-	return float32(x*int16(ScaleFactor)/20) + offsetX
+	return float32(float64(x)/mapUnitsPerPixel) + offsetX
 }
 
 // Remap WAD Y-coordinate match resolution, make more of the map visible and invert (in WAD: positive-y values mean up,
 // not down).
 func remapY(y int16) float32 {
-	return float32(-y*int16(ScaleFactor)/20) - offsetY
+	return float32(-float64(y)/mapUnitsPerPixel) - offsetY
 }
 
-func calculateMapOffset(player *Thing) {
-	offsetX = float32(ScreenCenterX) - float32(player.XPosition*int16(ScaleFactor)/20) + PlayerOffsetX
-	offsetY = -(float32(ScreenCenterY) - float32(-player.YPosition*int16(ScaleFactor)/20)) + PlayerOffsetY
+// calculateMapOffset centers the map on the player and zooms it so the same area is visible at every resolution
+func calculateMapOffset(screen *Framebuffer) {
+	mapUnitsPerPixel = MapUnitsPerPixel * float64(DefaultResolution.Width) / float64(screen.Width)
+	offsetX = float32(float64(screen.Width)/2 - PlayerX/mapUnitsPerPixel)
+	offsetY = -float32(float64(screen.Height)/2 + PlayerY/mapUnitsPerPixel)
 }
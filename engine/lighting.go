@@ -0,0 +1,63 @@
+package engine
+
+// Light diminishing of the original engine: sector light levels are reduced to 16 steps, and each step darkens with
+// distance through a table of colormaps. Walls and sprites look up their colormap by scale, floors and ceilings by
+// distance.
+const (
+	lightLevels     = 16
+	lightSegShift   = 4 // sector light level to renderer light level
+	maxLightScale   = 48
+	maxLightZ       = 128
+	lightZUnit      = 16 // map units per entry of the distance table
+	lightScaleUnits = 16 // entries of the scale table per unit of scale
+	distMap         = 2
+)
+
+// initLightTables fills the colormap tables for diminishing light by scale and distance.
+func (r *Renderer) initLightTables() {
+	r.scaleLight = make([][maxLightScale]*Colormap, lightLevels)
+	r.zLight = make([][maxLightZ]*Colormap, lightLevels)
+	for i := 0; i < lightLevels; i++ {
+		startMap := (lightLevels - 1 - i) * 2 * NumLightLevels / lightLevels
+		for j := 0; j < maxLightZ; j++ {
+			scale := float64(originalProjection) / float64((j+1)*lightZUnit) * lightScaleUnits
+			level := startMap - int(scale)/distMap
+			r.zLight[i][j] = &r.colormaps[min(max(level, 0), NumLightLevels-1)]
+		}
+		for j := 0; j < maxLightScale; j++ {
+			level := startMap - j/distMap
+			r.scaleLight[i][j] = &r.colormaps[min(max(level, 0), NumLightLevels-1)]
+		}
+	}
+}
+
+// lightIndex returns the renderer light level for a sector light level, adjusted by the given number of steps.
+func lightIndex(lightLevel int16, adjust int) int {
+	return min(max(int(lightLevel>>lightSegShift)+adjust, 0), lightLevels-1)
+}
+
+// scaleColormap returns the colormap of walls and sprites at the given light index and scale. Scales are compared at
+// the original resolution, so distant walls darken alike at every resolution.
+func (r *Renderer) scaleColormap(light int, scale float64) *Colormap {
+	scale *= originalProjection / r.yProjection
+	return r.scaleLight[light][min(max(int(scale*lightScaleUnits), 0), maxLightScale-1)]
+}
+
+// distanceColormap returns the colormap of floors and ceilings at the given light index and distance.
+func (r *Renderer) distanceColormap(light int, distance float64) *Colormap {
+	return r.zLight[light][min(max(int(distance/lightZUnit), 0), maxLightZ-1)]
+}
+
+// segLightIndex returns the light index of the current seg's walls. Like the original engine, walls along the x-axis
+// are made a step darker and walls along the y-axis a step lighter, to give rooms some contrast.
+func (r *Renderer) segLightIndex() int {
+	v1 := r.currentMap.Vertexes[r.currentSeg.startingVertexNumber]
+	v2 := r.currentMap.Vertexes[r.currentSeg.endingVertexNumber]
+	adjust := 0
+	if v1.YPosition == v2.YPosition {
+		adjust = -1
+	} else if v1.XPosition == v2.XPosition {
+		adjust = 1
+	}
+	return lightIndex(r.frontSector.lightLevel, adjust)
+}
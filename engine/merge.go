@@ -0,0 +1,89 @@
+package engine
+
+// Namespace markers that are coalesced when PWADs are merged. PWADs may use the doubled SS_/FF_ variants so that they
+// do not replace the IWAD's markers when loaded by the original engine.
+var mergedNamespaces = [][2]string{
+	{"S_START", "S_END"},
+	{"F_START", "F_END"},
+}
+
+// OpenFiles opens the IWAD at the given path and merges the given PWADs on top of it in order, as with the -file
+// command line parameter of the original engine.
+func OpenFiles(iwadPath string, pwadPaths ...string) (*Wad, error) {
+	wad, err := Open(iwadPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, pwadPath := range pwadPaths {
+		pwad, err := Open(pwadPath)
+		if err != nil {
+			wad.Close()
+			return nil, err
+		}
+		wad.Merge(pwad)
+	}
+	return wad, nil
+}
+
+// Merge appends the lumps of other to the directory of w. Since name lookups search backwards, later lumps override
+// earlier ones of the same name and a map found in other replaces the one in w as a whole. Sprite and flat lumps are
+// gathered into a single S_START/S_END and F_START/F_END block so that they are found by namespace lookups.
+func (w *Wad) Merge(other *Wad) {
+	fileOffset := len(w.files)
+	w.files = append(w.files, other.files...)
+	for _, lump := range other.lumps {
+		lump.file += fileOffset
+		w.lumps = append(w.lumps, lump)
+	}
+
+	for _, namespace := range mergedNamespaces {
+		w.coalesceNamespace(namespace[0], namespace[1])
+	}
+	clear(w.pictures)
+}
+
+// coalesceNamespace moves all lumps between start and end markers (including the SS_START/FF_START style variants)
+// into a single block at the end of the directory, keeping their relative order. Empty lumps inside a namespace are
+// sub-markers such as F1_START and are dropped. A start marker without an end marker is left in place together with
+// the lumps following it.
+func (w *Wad) coalesceNamespace(startMarker string, endMarker string) {
+	var unmarked []Directory
+	var marked []Directory
+	namespaceStart := -1 // index in w.lumps of the open start marker
+	var pending []Directory
+	for i, lump := range w.lumps {
+		if namespaceStart < 0 && isNamespaceMarker(lump.name, startMarker) {
+			namespaceStart = i
+			pending = pending[:0]
+			continue
+		}
+		if namespaceStart >= 0 {
+			if isNamespaceMarker(lump.name, endMarker) {
+				marked = append(marked, pending...)
+				namespaceStart = -1
+			} else if lump.size > 0 {
+				pending = append(pending, lump)
+			}
+			continue
+		}
+		unmarked = append(unmarked, lump)
+	}
+	if namespaceStart >= 0 {
+		unmarked = append(unmarked, w.lumps[namespaceStart:]...)
+	}
+	if len(marked) == 0 {
+		return
+	}
+
+	lumps := make([]Directory, 0, len(unmarked)+len(marked)+2)
+	lumps = append(lumps, unmarked...)
+	lumps = append(lumps, Directory{name: startMarker})
+	lumps = append(lumps, marked...)
+	lumps = append(lumps, Directory{name: endMarker})
+	w.lumps = lumps
+}
+
+// isNamespaceMarker reports whether name is the given marker or its doubled variant (SS_START for S_START).
+func isNamespaceMarker(name string, marker string) bool {
+	return name == marker || (len(name) > 1 && name[0] == marker[0] && name[1:] == marker)
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeReplacesMapsAndCoalescesNamespaces(t *testing.T) {
+	iwad := loadWad(t, "IWAD", []testLump{
+		{name: "PLAYPAL", data: []byte("iwad palette")},
+		{name: "S_START"},
+		{name: "TROOA1", data: []byte("iwad sprite")},
+		{name: "S_END"},
+		{name: "F_START"},
+		{name: "FLOOR0_1", data: []byte("iwad flat")},
+		{name: "NUKAGE1", data: []byte("iwad flat")},
+		{name: "F_END"},
+		{name: "E1M1"},
+		{name: "THINGS", data: []byte("iwad things")},
+	})
+	pwad := loadWad(t, "PWAD", []testLump{
+		{name: "SS_START"},
+		{name: "BON1A0", data: []byte("pwad sprite")},
+		{name: "SS_END"},
+		{name: "FF_START"},
+		{name: "F1_START"},
+		{name: "NUKAGE1", data: []byte("pwad flat")},
+		{name: "F1_END"},
+		{name: "FF_END"},
+		{name: "E1M1"},
+		{name: "THINGS", data: []byte("pwad things")},
+	})
+	iwad.Merge(pwad)
+
+	names := lumpNames(iwad)
+	for _, marker := range []string{"SS_START", "SS_END", "FF_START", "FF_END", "F1_START", "F1_END"} {
+		if slices.Contains(names, marker) {
+			t.Errorf("marker %s kept after merging: %v", marker, names)
+		}
+	}
+	if _, ok := iwad.FindLumpInNamespace("TROOA1", "S_START", "S_END"); !ok {
+		t.Errorf("TROOA1 of the IWAD lost from the sprite namespace")
+	}
+	index, ok := iwad.FindLumpInNamespace("BON1A0", "S_START", "S_END")
+	if !ok || iwad.ReadDirectoryForLumpIndex(index).file != 1 {
+		t.Errorf("BON1A0 of the PWAD not found in the sprite namespace")
+	}
+	index, ok = iwad.FindLumpInNamespace("NUKAGE1", "F_START", "F_END")
+	if !ok || iwad.ReadDirectoryForLumpIndex(index).file != 1 {
+		t.Errorf("NUKAGE1 of the PWAD not found in the flat namespace")
+	}
+	if _, ok := iwad.FindLumpInNamespace("FLOOR0_1", "F_START", "F_END"); !ok {
+		t.Errorf("FLOOR0_1 of the IWAD lost from the flat namespace")
+	}
+	index, _ = iwad.ReadLumpIndexForName("E1M1")
+	if iwad.ReadDirectoryForLumpIndex(index).file != 1 {
+		t.Errorf("E1M1 not replaced by the PWAD")
+	}
+	if data, _ := iwad.ReadLumpDataForName("THINGS"); string(data) != "pwad things" {
+		t.Errorf("THINGS = %q, want the PWAD's", data)
+	}
+}
+
+func TestMergeUnterminatedNamespace(t *testing.T) {
+	iwad := loadWad(t, "IWAD", testIwadLumps())
+	pwadLumps := []testLump{
+		{name: "S_START"},
+		{name: "BON1A0", data: encodePicture(8, 8, 4, 8, func(x int, y int) int { return 7 })},
+		{name: Texture1Lump, data: []byte{0, 0, 0, 0}},
+	}
+	pwadLumps = append(pwadLumps, testMapLumps("E1M2")...)
+	iwad.Merge(loadWad(t, "PWAD", pwadLumps))
+
+	names := lumpNames(iwad)
+	want := append([]string{"S_START", "BON1A0", Texture1Lump}, lumpNamesOf(testMapLumps("E1M2"))...)
+	start := -1
+	for i, name := range names {
+		if name == Texture1Lump {
+			start = i - 2 // the last TEXTURE1 is the PWAD's
+		}
+	}
+	if start < 0 || start+len(want) > len(names) || !slices.Equal(names[start:start+len(want)], want) {
+		t.Errorf("lumps after an unterminated S_START moved: %v", names)
+	}
+	if _, ok := iwad.FindLumpInNamespace(Texture1Lump, SpriteStartMarker, SpriteEndMarker); ok {
+		t.Errorf("TEXTURE1 moved into the sprite namespace")
+	}
+	if got := iwad.ListMaps(); !slices.Equal(got, []string{"E1M1", "E1M2"}) {
+		t.Errorf("ListMaps = %v", got)
+	}
+}
+
+func lumpNamesOf(lumps []testLump) []string {
+	names := make([]string, len(lumps))
+	for i, lump := range lumps {
+		names[i] = lump.name
+	}
+	return names
+}
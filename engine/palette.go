@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"image/color"
+)
+
+// Palette lumps see: https://doomwiki.org/wiki/PLAYPAL and https://doomwiki.org/wiki/COLORMAP
+const (
+	PlaypalLump  = "PLAYPAL"
+	ColormapLump = "COLORMAP"
+
+	PaletteBlockSize  int32 = 256 * 3
+	ColormapBlockSize int32 = 256
+
+	NumPalettes  = 14
+	NumColormaps = 34
+
+	// NumLightLevels is the number of colormaps from full brightness (0) to darkness (31)
+	NumLightLevels          = 32
+	InvulnerabilityColormap = 32
+)
+
+// Palettes used to tint the screen when the player takes damage, picks up an item or wears a radiation suit
+const (
+	StartRedPalettes   = 1
+	NumRedPalettes     = 8
+	StartBonusPalettes = 9
+	NumBonusPalettes   = 4
+	RadiationPalette   = 13
+)
+
+// Palette maps the 256 color indexes used by all WAD graphics to RGB colors.
+type Palette [256]color.RGBA
+
+// Colormap remaps color indexes to darker (or, for the invulnerability effect, inverted) ones of the same palette.
+type Colormap [256]byte
+
+// ReadPalettes decodes the palettes of the PLAYPAL lump, 14 of them in the IWADs.
+func (w *Wad) ReadPalettes() ([]Palette, error) {
+	directory, data, err := w.readLumpRecords(PlaypalLump, PaletteBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	}
+
+	palettes := make([]Palette, len(data)/int(PaletteBlockSize))
+	for i := range palettes {
+		for c := 0; c < 256; c++ {
+			entry := data[i*int(PaletteBlockSize)+c*3:]
+			palettes[i][c] = color.RGBA{R: entry[0], G: entry[1], B: entry[2], A: 0xff}
+		}
+	}
+	return palettes, nil
+}
+
+// ReadColormaps decodes the light tables of the COLORMAP lump: 32 light levels, the invulnerability map and an unused
+// all-black one in the IWADs.
+func (w *Wad) ReadColormaps() ([]Colormap, error) {
+	directory, data, err := w.readLumpRecords(ColormapLump, ColormapBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	}
+
+	colormaps := make([]Colormap, len(data)/int(ColormapBlockSize))
+	for i := range colormaps {
+		copy(colormaps[i][:], data[i*int(ColormapBlockSize):])
+	}
+	return colormaps, nil
+}
+
+// ColorPalette converts the palette for use with image.Paletted.
+func (p *Palette) ColorPalette() color.Palette {
+	colors := make(color.Palette, len(p))
+	for i, c := range p {
+		colors[i] = c
+	}
+	return colors
+}
+
+// TintPalette returns the index of the palette to show for the given player state, following the original status
+// bar: damage turns the screen red, picking up items gold and the radiation suit green. The counts decrease by one
+// every tic.
+func TintPalette(damageCount int, bonusCount int, radiationSuitTics int) int {
+	if damageCount > 0 {
+		return StartRedPalettes + min((damageCount+7)>>3, NumRedPalettes-1)
+	}
+	if bonusCount > 0 {
+		return StartBonusPalettes + min((bonusCount+7)>>3, NumBonusPalettes-1)
+	}
+	// the suit flashes when it is about to run out
+	if radiationSuitTics > 4*32 || radiationSuitTics&8 != 0 {
+		return RadiationPalette
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+)
+
+func TestReadPalettes(t *testing.T) {
+	playpal := make([]byte, NumPalettes*PaletteBlockSize)
+	for i := range playpal {
+		playpal[i] = byte(i / 3)
+	}
+	palettes, err := loadWad(t, "IWAD", []testLump{{name: PlaypalLump, data: playpal}}).ReadPalettes()
+	if err != nil {
+		t.Fatalf("ReadPalettes: %v", err)
+	}
+	if len(palettes) != NumPalettes {
+		t.Fatalf("got %d palettes, want %d", len(palettes), NumPalettes)
+	}
+	// every color is gray at its index counted across all palettes, i.e. 256+2 for color 2 of palette 1
+	if got, want := palettes[1][2], (color.RGBA{R: 2, G: 2, B: 2, A: 0xff}); got != want {
+		t.Errorf("palette 1 color 2 = %v, want %v", got, want)
+	}
+}
+
+func TestReadColormaps(t *testing.T) {
+	colormap := make([]byte, NumColormaps*ColormapBlockSize)
+	for i := range colormap {
+		colormap[i] = byte(i / int(ColormapBlockSize))
+	}
+	colormaps, err := loadWad(t, "IWAD", []testLump{{name: ColormapLump, data: colormap}}).ReadColormaps()
+	if err != nil {
+		t.Fatalf("ReadColormaps: %v", err)
+	}
+	if len(colormaps) != NumColormaps {
+		t.Fatalf("got %d colormaps, want %d", len(colormaps), NumColormaps)
+	}
+	if colormaps[InvulnerabilityColormap][255] != InvulnerabilityColormap {
+		t.Errorf("invulnerability colormap = %v", colormaps[InvulnerabilityColormap][:4])
+	}
+}
+
+func TestReadPaletteLumpSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		lump string
+		size int32
+		want error
+	}{
+		{"one palette", PlaypalLump, PaletteBlockSize, nil},
+		{"partial palette", PlaypalLump, PaletteBlockSize + 3, ErrLumpSizeNotMultiple},
+		{"empty palette", PlaypalLump, 0, ErrShortLump},
+		{"missing palette", "NOTPAL", PaletteBlockSize, ErrLumpNotFound},
+		{"one colormap", ColormapLump, ColormapBlockSize, nil},
+		{"partial colormap", ColormapLump, 2*ColormapBlockSize - 1, ErrLumpSizeNotMultiple},
+		{"empty colormap", ColormapLump, 0, ErrShortLump},
+		{"missing colormap", "NOTMAP", ColormapBlockSize, ErrLumpNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wad := loadWad(t, "IWAD", []testLump{{name: test.lump, data: make([]byte, test.size)}})
+			var err error
+			if test.lump == PlaypalLump || test.lump == "NOTPAL" {
+				_, err = wad.ReadPalettes()
+			} else {
+				_, err = wad.ReadColormaps()
+			}
+			if !errors.Is(err, test.want) {
+				t.Errorf("error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestTintPalette(t *testing.T) {
+	tests := []struct {
+		name                                   string
+		damageCount, bonusCount, radiationTics int
+		want                                   int
+	}{
+		{"no effect", 0, 0, 0, 0},
+		{"light damage", 1, 0, 0, StartRedPalettes + 1},
+		{"heavy damage", 100, 0, 0, StartRedPalettes + NumRedPalettes - 1},
+		{"damage over bonus", 10, 6, 0, StartRedPalettes + 2},
+		{"bonus", 0, 6, 0, StartBonusPalettes + 1},
+		{"large bonus", 0, 100, 0, StartBonusPalettes + NumBonusPalettes - 1},
+		{"bonus over radiation suit", 0, 1, 1000, StartBonusPalettes + 1},
+		{"radiation suit", 0, 0, 1000, RadiationPalette},
+		{"radiation suit running out, flash on", 0, 0, 8, RadiationPalette},
+		{"radiation suit running out, flash off", 0, 0, 7, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := TintPalette(test.damageCount, test.bonusCount, test.radiationTics); got != test.want {
+				t.Errorf("TintPalette(%d, %d, %d) = %d, want %d", test.damageCount, test.bonusCount,
+					test.radiationTics, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+const pictureHeaderSize = 8
+
+// postEnd terminates the list of posts of a picture column
+const postEnd = 0xff
+
+// Picture see: https://doomwiki.org/wiki/Picture_format
+//
+// Pictures (also called patches) are used for wall patches, sprites, the status bar, menus and fonts. They are stored
+// column by column, each column consisting of vertical runs of opaque pixels (posts); everything else is transparent.
+type Picture struct {
+	Name       string
+	Width      int
+	Height     int
+	LeftOffset int
+	TopOffset  int
+	columns    [][]Post
+}
+
+// Post is a vertical run of opaque pixels in a picture column.
+type Post struct {
+	TopDelta int // row of the first pixel
+	Pixels   []byte
+}
+
+// ReadPicture decodes the picture lump with the given name. Decoded pictures are cached by lump name.
+func (w *Wad) ReadPicture(name string) (*Picture, error) {
+	name = strings.ToUpper(name)
+	if picture, ok := w.pictures[name]; ok {
+		return picture, nil
+	}
+	index, ok := w.ReadLumpIndexForName(name)
+	if !ok {
+		return nil, &LumpError{Lump: name, Offset: 0, Err: ErrLumpNotFound}
+	}
+	picture, err := w.readPictureForLumpIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	w.pictures[name] = picture
+	return picture, nil
+}
+
+func (w *Wad) readPictureForLumpIndex(index int) (*Picture, error) {
+	directory := w.ReadDirectoryForLumpIndex(index)
+	data, err := w.ReadLumpData(directory)
+	if err != nil {
+		return nil, err
+	}
+	return decodePicture(directory, data)
+}
+
+func decodePicture(directory Directory, data []byte) (*Picture, error) {
+	shortLump := &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	if len(data) < pictureHeaderSize {
+		return nil, shortLump
+	}
+	picture := &Picture{
+		Name:       directory.name,
+		Width:      int(readInt[int16](data[0:2])),
+		Height:     int(readInt[int16](data[2:4])),
+		LeftOffset: int(readInt[int16](data[4:6])),
+		TopOffset:  int(readInt[int16](data[6:8])),
+	}
+	if picture.Width < 0 || picture.Height < 0 || len(data) < pictureHeaderSize+4*picture.Width {
+		return nil, shortLump
+	}
+
+	picture.columns = make([][]Post, picture.Width)
+	for x := 0; x < picture.Width; x++ {
+		offset := int(readInt[int32](data[pictureHeaderSize+4*x : pictureHeaderSize+4*x+4]))
+		top := -1
+		for {
+			if offset < 0 || offset >= len(data) {
+				return nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrIndexOutOfRange}
+			}
+			if data[offset] == postEnd {
+				break
+			}
+			if offset+2 > len(data) {
+				return nil, shortLump
+			}
+			// tall patches (over 254 pixels) store the offset relative to the previous post once it would not
+			// increase anymore
+			topDelta := int(data[offset])
+			if topDelta <= top {
+				top += topDelta
+			} else {
+				top = topDelta
+			}
+			length := int(data[offset+1])
+			// each post is followed by its length, a padding byte, the pixels and another padding byte
+			if offset+4+length > len(data) {
+				return nil, shortLump
+			}
+			picture.columns[x] = append(picture.columns[x], Post{TopDelta: top, Pixels: data[offset+3 : offset+3+length]})
+			offset += 4 + length
+		}
+	}
+	return picture, nil
+}
+
+// Column returns the posts of the column at x.
+func (p *Picture) Column(x int) []Post {
+	return p.columns[x]
+}
+
+// Paletted returns the picture as a paletted image along with a mask of its opaque pixels. Transparent pixels are set
+// to color index 0.
+func (p *Picture) Paletted(palette *Palette) (*image.Paletted, *image.Alpha) {
+	img := image.NewPaletted(image.Rect(0, 0, p.Width, p.Height), palette.ColorPalette())
+	mask := image.NewAlpha(img.Rect)
+	p.forEachPixel(func(x int, y int, index byte) {
+		img.SetColorIndex(x, y, index)
+		mask.SetAlpha(x, y, color.Alpha{A: 0xff})
+	})
+	return img, mask
+}
+
+// Image returns the picture as an image with transparent pixels.
+func (p *Picture) Image(palette *Palette) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, p.Width, p.Height))
+	p.forEachPixel(func(x int, y int, index byte) {
+		c := palette[index]
+		img.SetNRGBA(x, y, color.NRGBA{R: c.R, G: c.G, B: c.B, A: 0xff})
+	})
+	return img
+}
+
+func (p *Picture) forEachPixel(f func(x int, y int, index byte)) {
+	for x, posts := range p.columns {
+		for _, post := range posts {
+			for i, index := range post.Pixels {
+				if y := post.TopDelta + i; y < p.Height {
+					f(x, y, index)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDecodePicture(t *testing.T) {
+	// a 3x4 picture with a transparent column and a hole in the middle column
+	data := encodePicture(3, 4, 1, 4, func(x int, y int) int {
+		if x == 2 || (x == 1 && y == 1) {
+			return -1
+		}
+		return 10*x + y
+	})
+	picture, err := decodePicture(Directory{name: "TEST"}, data)
+	if err != nil {
+		t.Fatalf("decodePicture: %v", err)
+	}
+	if picture.Width != 3 || picture.Height != 4 || picture.LeftOffset != 1 || picture.TopOffset != 4 {
+		t.Errorf("header %d x %d at (%d, %d)", picture.Width, picture.Height, picture.LeftOffset, picture.TopOffset)
+	}
+	middle := picture.Column(1)
+	if len(middle) != 2 || middle[0].TopDelta != 0 || !slices.Equal(middle[0].Pixels, []byte{10}) ||
+		middle[1].TopDelta != 2 || !slices.Equal(middle[1].Pixels, []byte{12, 13}) {
+		t.Errorf("column 1 posts %+v", middle)
+	}
+	if len(picture.Column(2)) != 0 {
+		t.Errorf("transparent column has posts %+v", picture.Column(2))
+	}
+
+	var palette Palette
+	img, mask := picture.Paletted(&palette)
+	if img.ColorIndexAt(0, 3) != 3 || img.ColorIndexAt(1, 2) != 12 {
+		t.Errorf("pixels %d and %d, want 3 and 12", img.ColorIndexAt(0, 3), img.ColorIndexAt(1, 2))
+	}
+	if mask.AlphaAt(1, 1).A != 0 || mask.AlphaAt(2, 0).A != 0 || mask.AlphaAt(1, 0).A != 0xff {
+		t.Errorf("mask does not match the opaque pixels")
+	}
+}
+
+func TestDecodeTallPicture(t *testing.T) {
+	// one column of posts at rows 200, 254 and, relative to the previous post since it does not increase, 264
+	post := func(topDelta byte, length int) []byte {
+		return append(append([]byte{topDelta, byte(length), 0}, make([]byte, length)...), 0)
+	}
+	data := le(int16(1), int16(300), int16(0), int16(0), int32(12))
+	data = append(data, post(200, 10)...)
+	data = append(data, post(254, 5)...)
+	data = append(data, post(10, 3)...)
+	data = append(data, postEnd)
+	picture, err := decodePicture(Directory{name: "TALL"}, data)
+	if err != nil {
+		t.Fatalf("decodePicture: %v", err)
+	}
+	var rows []int
+	for _, post := range picture.Column(0) {
+		rows = append(rows, post.TopDelta)
+	}
+	if !slices.Equal(rows, []int{200, 254, 264}) {
+		t.Errorf("post rows %v, want [200 254 264]", rows)
+	}
+}
+
+func TestDecodePictureErrors(t *testing.T) {
+	valid := encodePicture(2, 2, 0, 0, func(x int, y int) int { return x + y })
+	// a picture with one column whose offset points to the given column data
+	column := func(offset int32, columnData ...byte) []byte {
+		return append(le(int16(1), int16(2), int16(0), int16(0), offset), columnData...)
+	}
+	tests := map[string]struct {
+		data []byte
+		want error
+	}{
+		"short header":          {valid[:pictureHeaderSize-1], ErrShortLump},
+		"short column offsets":  {valid[:pictureHeaderSize+4], ErrShortLump},
+		"negative width":        {le(int16(-1), int16(2), int16(0), int16(0)), ErrShortLump},
+		"short post header":     {column(12, 0), ErrShortLump},
+		"short post pixels":     {column(12, 0, 2, 0, 5), ErrShortLump},
+		"unterminated column":   {column(12, 0, 1, 0, 5, 0), ErrIndexOutOfRange},
+		"column past lump end":  {column(100, postEnd), ErrIndexOutOfRange},
+		"column at lump end":    {column(13, postEnd), ErrIndexOutOfRange},
+		"negative column start": {column(-4, postEnd), ErrIndexOutOfRange},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodePicture(Directory{name: "BAD", filepos: 40}, test.data)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("decodePicture error = %v, want %v", err, test.want)
+			}
+			var lumpError *LumpError
+			if !errors.As(err, &lumpError) || lumpError.Lump != "BAD" || lumpError.Offset != 40 {
+				t.Errorf("error %v does not name the lump", err)
+			}
+		})
+	}
+}
+
+func TestReadPictureCache(t *testing.T) {
+	wad := loadWad(t, "IWAD", []testLump{{name: "PIC", data: encodePicture(1, 1, 0, 0, func(int, int) int { return 1 })}})
+	first, err := wad.ReadPicture("PIC")
+	if err != nil {
+		t.Fatalf("ReadPicture: %v", err)
+	}
+	if second, _ := wad.ReadPicture("pic"); second != first {
+		t.Errorf("picture decoded again instead of cached")
+	}
+	if _, err := wad.ReadPicture("NOPIC"); !errors.Is(err, ErrLumpNotFound) {
+		t.Errorf("ReadPicture error = %v, want %v", err, ErrLumpNotFound)
+	}
+}
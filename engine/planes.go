@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"math"
+)
+
+// visplane is a floor or ceiling area of one height, flat and light level, collected while drawing walls: for each
+// column it holds the rows between the walls above and below. Columns without rows have top > bottom.
+type visplane struct {
+	height     int16
+	flat       int // index in the flat table, -1 if the flat is missing
+	lightLevel int16
+	minX       int
+	maxX       int
+	top        []int // indexed by x+1, so the columns on either side can be read as empty
+	bottom     []int
+}
+
+// clearPlanes removes the visplanes of the last frame.
+func (r *Renderer) clearPlanes() {
+	r.numVisplanes = 0
+	for x := range r.spanStart {
+		r.spanStart[x] = 0
+	}
+}
+
+// findPlane returns the visplane with the given attributes, creating it if necessary.
+func (r *Renderer) findPlane(height int16, flatName string, lightLevel int16) *visplane {
+	flat, ok := r.flats.Index(flatName)
+	if !ok {
+		flat = -1
+	}
+	if flat == r.skyFlat {
+		// all sky is drawn the same, regardless of height and light
+		height = 0
+		lightLevel = 0
+	}
+	for _, plane := range r.visplanes[:r.numVisplanes] {
+		if plane.height == height && plane.flat == flat && plane.lightLevel == lightLevel {
+			return plane
+		}
+	}
+	return r.newPlane(height, flat, lightLevel, r.Width, -1)
+}
+
+func (r *Renderer) newPlane(height int16, flat int, lightLevel int16, minX int, maxX int) *visplane {
+	// planes of earlier frames are reused
+	if r.numVisplanes == len(r.visplanes) {
+		r.visplanes = append(r.visplanes, &visplane{top: make([]int, r.Width+2), bottom: make([]int, r.Width+2)})
+	}
+	plane := r.visplanes[r.numVisplanes]
+	r.numVisplanes++
+	plane.height = height
+	plane.flat = flat
+	plane.lightLevel = lightLevel
+	plane.minX = minX
+	plane.maxX = maxX
+	for x := range plane.top {
+		plane.top[x] = r.Height
+		plane.bottom[x] = -1
+	}
+	return plane
+}
+
+// checkPlane returns a visplane to mark the columns [start, stop] in: the given one extended to the range if none of
+// its columns there are in use, otherwise a new one with the same attributes.
+func (r *Renderer) checkPlane(plane *visplane, start int, stop int) *visplane {
+	intersectLow, unionLow := max(start, plane.minX), min(start, plane.minX)
+	intersectHigh, unionHigh := min(stop, plane.maxX), max(stop, plane.maxX)
+
+	x := intersectLow
+	for ; x <= intersectHigh; x++ {
+		if plane.top[x+1] <= plane.bottom[x+1] {
+			break
+		}
+	}
+	if x > intersectHigh {
+		plane.minX = unionLow
+		plane.maxX = unionHigh
+		return plane
+	}
+	return r.newPlane(plane.height, plane.flat, plane.lightLevel, start, stop)
+}
+
+// drawPlanes draws the visplanes of the frame as horizontal spans.
+func (r *Renderer) drawPlanes() {
+	for _, plane := range r.visplanes[:r.numVisplanes] {
+		if plane.minX > plane.maxX || plane.flat < 0 {
+			continue
+		}
+		if plane.flat == r.skyFlat {
+			r.drawSky(plane)
+			continue
+		}
+		r.planeFlat = r.flats.Flat(plane.flat)
+		r.planeHeight = math.Abs(float64(plane.height) - r.viewZ)
+		r.planeLight = lightIndex(plane.lightLevel, 0)
+
+		// the columns on either side of the plane are empty, closing all spans
+		for x := plane.minX; x <= plane.maxX+1; x++ {
+			r.makeSpans(x, plane.top[x], plane.bottom[x], plane.top[x+1], plane.bottom[x+1])
+		}
+	}
+}
+
+// makeSpans finishes the spans of the rows the plane covered in column x-1 but not in column x, and starts spans for
+// the rows newly covered in column x.
+func (r *Renderer) makeSpans(x int, t1 int, b1 int, t2 int, b2 int) {
+	for t1 < t2 && t1 <= b1 {
+		r.mapPlane(t1, r.spanStart[t1], x-1)
+		t1++
+	}
+	for b1 > b2 && b1 >= t1 {
+		r.mapPlane(b1, r.spanStart[b1], x-1)
+		b1--
+	}
+	for t2 < t1 && t2 <= b2 {
+		r.spanStart[t2] = x
+		t2++
+	}
+	for b2 > b1 && b2 >= t2 {
+		r.spanStart[b2] = x
+		b2--
+	}
+}
+
+// mapPlane draws the span [x1, x2] of row y of the current plane. All pixels of a row lie at the same distance, so
+// the flat is stepped through linearly.
+func (r *Renderer) mapPlane(y int, x1 int, x2 int) {
+	dy := math.Abs(float64(y) - r.centerY + 0.5)
+	distance := r.planeHeight * r.yProjection / dy
+
+	// position of the first pixel in the map, and the step to the next pixel along the row
+	angle := r.viewAngle + r.xToViewAngle[x1]
+	length := distance / math.Cos(r.xToViewAngle[x1].signedRadians())
+	xFrac := r.viewX + math.Cos(angle.radians())*length
+	yFrac := -r.viewY - math.Sin(angle.radians())*length
+	xStep := distance * math.Cos((r.viewAngle - bamAngle90).radians()) / r.projection
+	yStep := -distance * math.Sin((r.viewAngle - bamAngle90).radians()) / r.projection
+
+	colormap := r.distanceColormap(r.planeLight, distance)
+	for x := x1; x <= x2; x++ {
+		spot := int(math.Floor(yFrac))&(FlatSize-1)*FlatSize + int(math.Floor(xFrac))&(FlatSize-1)
+		r.Framebuffer.Pixels[y*r.Width+x] = colormap[r.planeFlat.Pixels[spot]]
+		xFrac += xStep
+		yFrac += yStep
+	}
+}
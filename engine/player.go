@@ -3,9 +3,23 @@ package engine
 const (
 	PlayerRotationSpeed float64 = 2
 	PlayerMovementSpeed float64 = 2
+	PlayerViewHeight    float64 = 41
+	PlayerStartType     int16   = 1
 )
 
 // only for testing
-var PlayerOffsetX float32 = 0
-var PlayerOffsetY float32 = 0
-var PlayerAngle float64 = 180
+var PlayerX float64 = 0
+var PlayerY float64 = 0
+var PlayerAngle float64 = 90 // degrees counter-clockwise from east, like thing directions
+
+// SpawnPlayer places the player at the player 1 start of the given map.
+func SpawnPlayer(currentMap *Map) {
+	for _, thing := range currentMap.Things {
+		if thing.ThingType == PlayerStartType {
+			PlayerX = float64(thing.XPosition)
+			PlayerY = float64(thing.YPosition)
+			PlayerAngle = float64(thing.Direction)
+			return
+		}
+	}
+}
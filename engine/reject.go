@@ -0,0 +1,27 @@
+package engine
+
+// Reject see: https://doomwiki.org/wiki/Reject
+//
+// The REJECT lump is a bit matrix with one row per sector. A set bit at column b of row a means that no monster in
+// sector a can see a player in sector b, so the expensive line of sight check can be skipped.
+type Reject struct {
+	numSectors int
+	data       []byte
+}
+
+func readReject(data []byte, numSectors int) Reject {
+	return Reject{numSectors: numSectors, data: data}
+}
+
+// CanSectorSee reports whether sector b may be visible from sector a. Bits missing from a truncated REJECT lump are
+// treated as visible.
+func (r Reject) CanSectorSee(a int, b int) bool {
+	if a < 0 || b < 0 || a >= r.numSectors || b >= r.numSectors {
+		return true
+	}
+	bit := a*r.numSectors + b
+	if bit/8 >= len(r.data) {
+		return true
+	}
+	return r.data[bit/8]&(1<<(bit%8)) == 0
+}
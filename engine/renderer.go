@@ -0,0 +1,499 @@
+package engine
+
+import (
+	"math"
+)
+
+// bam is a binary angle measurement as used by the original engine: the full circle maps onto the range of uint32,
+// so angle differences wrap around without normalization.
+type bam uint32
+
+const (
+	bamAngle90  bam = 0x40000000
+	bamAngle180 bam = 0x80000000
+)
+
+func bamFromRadians(angle float64) bam {
+	return bam(int64(math.Round(angle / (2 * math.Pi) * (1 << 32))))
+}
+
+func bamFromDegrees(angle float64) bam {
+	return bamFromRadians(DegToRad(angle))
+}
+
+// bamFromSegAngle converts the 16-bit angle stored in SEGS.
+func bamFromSegAngle(angle int16) bam {
+	return bam(uint16(angle)) << 16
+}
+
+func (a bam) radians() float64 {
+	return float64(a) / (1 << 32) * 2 * math.Pi
+}
+
+// signedRadians interprets the angle as lying between -180 and 180 degrees.
+func (a bam) signedRadians() float64 {
+	return float64(int32(a)) / (1 << 32) * 2 * math.Pi
+}
+
+func (a bam) abs() bam {
+	if int32(a) < 0 {
+		return -a
+	}
+	return a
+}
+
+// clipRange is a range of screen columns [first, last] already covered by solid walls.
+type clipRange struct {
+	first int
+	last  int
+}
+
+// Renderer draws the player's view of a map into a palette-indexed framebuffer, walking the BSP tree front to back
+// like the original engine.
+type Renderer struct {
+	Width       int
+	Height      int
+	Framebuffer *Framebuffer
+	resolution  Resolution
+
+	centerX      float64
+	centerY      float64
+	projection   float64 // distance of the projection plane in columns
+	yProjection  float64 // distance of the projection plane in rows, differing for pixels that are not square
+	clipAngle    bam     // half the horizontal field of view
+	xToViewAngle []bam   // view angle of the left edge of each column, relative to the view direction
+
+	colormaps  []Colormap
+	scaleLight [][maxLightScale]*Colormap // per light index, by scale
+	zLight     [][maxLightZ]*Colormap     // per light index, by distance
+	flats      *Flats
+	textures   *Textures
+	sprites    *Sprites
+	skyFlat    int      // index of F_SKY1 in the flat table, -1 if missing
+	skyTexture *Texture // sky of the current map
+
+	// state of the frame being rendered
+	currentMap  *Map
+	viewX       float64
+	viewY       float64
+	viewZ       float64
+	viewAngle   bam
+	solidSegs   []clipRange
+	ceilingClip []int // per column, the lowest row covered from above
+	floorClip   []int // per column, the highest row covered from below
+	frontSector *Sector
+	backSector  *Sector // nil for one-sided segs
+	currentSeg  *Seg
+	rwAngle1    bam // angle from the viewer to the first vertex of the current seg
+
+	visplanes    []*visplane // planes of all frames so far, the first numVisplanes used in this one
+	numVisplanes int
+	floorPlane   *visplane // nil if the floor of the current subsector is not visible
+	ceilingPlane *visplane // nil if the ceiling of the current subsector is not visible
+	spanStart    []int     // per row, the first column of the span being collected
+	planeFlat    *Flat
+	planeHeight  float64 // height of the plane being drawn above or below the viewer
+	planeLight   int
+
+	drawSegs          []drawSeg
+	openings          []int // storage for the clip arrays of drawsegs
+	screenHeightArray []int // clip array of columns covered from above
+	negOneArray       []int // clip array of columns covered from below
+	vissprites        []vissprite
+	spriteTopClip     []int
+	spriteBottomClip  []int
+	sectorThings      [][]Thing // things of the current map by sector
+	spritesAdded      []int     // per sector, the last frame its things have been projected in
+	frame             int
+}
+
+// NewRenderer creates a renderer drawing at the given resolution, using the palette, light tables, flats, wall
+// textures and sprites of the given WAD.
+func NewRenderer(wad *Wad, resolution Resolution) (*Renderer, error) {
+	if err := resolution.validate(); err != nil {
+		return nil, err
+	}
+	palettes, err := wad.ReadPalettes()
+	if err != nil {
+		return nil, err
+	}
+	colormaps, err := wad.ReadColormaps()
+	if err != nil {
+		return nil, err
+	}
+	flats, err := wad.ReadFlats()
+	if err != nil {
+		return nil, err
+	}
+	textures, err := wad.ReadTextures()
+	if err != nil {
+		return nil, err
+	}
+	sprites, err := wad.ReadSprites()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{
+		Framebuffer: NewFramebuffer(resolution.Width, resolution.Height, &palettes[0]),
+		colormaps:   colormaps,
+		flats:       flats,
+		textures:    textures,
+		sprites:     sprites,
+	}
+	skyFlat, ok := flats.Index(SkyFlatName)
+	if !ok {
+		skyFlat = -1
+	}
+	r.skyFlat = skyFlat
+	if err := r.SetResolution(resolution); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetResolution changes the size of the framebuffer and the projection. The framebuffer is replaced, keeping its
+// palette.
+func (r *Renderer) SetResolution(resolution Resolution) error {
+	if err := resolution.validate(); err != nil {
+		return err
+	}
+	r.resolution = resolution
+	r.Width = resolution.Width
+	r.Height = resolution.Height
+	if r.Framebuffer.Width != r.Width || r.Framebuffer.Height != r.Height {
+		r.Framebuffer = NewFramebuffer(r.Width, r.Height, r.Framebuffer.Palette)
+	}
+
+	r.ceilingClip = make([]int, r.Width)
+	r.floorClip = make([]int, r.Width)
+	r.spanStart = make([]int, r.Height)
+	r.visplanes = nil // their columns are sized for the old width
+	r.screenHeightArray = make([]int, r.Width)
+	r.negOneArray = make([]int, r.Width)
+	r.spriteTopClip = make([]int, r.Width)
+	r.spriteBottomClip = make([]int, r.Width)
+	for x := range r.screenHeightArray {
+		r.screenHeightArray[x] = r.Height
+		r.negOneArray[x] = -1
+	}
+	r.initProjection()
+	r.initLightTables()
+	return nil
+}
+
+// Resolution returns the resolution the renderer draws at.
+func (r *Renderer) Resolution() Resolution {
+	return r.resolution
+}
+
+// initProjection sets up the mapping between view angles and screen columns. FieldOfView applies to a 4:3 display,
+// wider displays see more to the sides. The vertical scale keeps the proportions of the original, whose pixels were
+// taller than wide.
+func (r *Renderer) initProjection() {
+	// like the original engine, the centre is a whole number of pixels, so that even for odd heights every row of a
+	// plane lies at least half a pixel above or below the eyes
+	r.centerX = float64(r.Width / 2)
+	r.centerY = float64(r.Height / 2)
+	tanHalfFieldOfView := math.Tan(DegToRad(HalfFieldOfView)) * r.resolution.DisplayAspect() / (4.0 / 3.0)
+	r.projection = r.centerX / tanHalfFieldOfView
+	r.yProjection = r.projection * OriginalPixelAspect / r.resolution.PixelAspect
+	r.clipAngle = bamFromRadians(math.Atan(tanHalfFieldOfView))
+
+	r.xToViewAngle = make([]bam, r.Width+1)
+	for x := range r.xToViewAngle {
+		r.xToViewAngle[x] = bamFromRadians(math.Atan((r.centerX - float64(x)) / r.projection))
+	}
+}
+
+// viewAngleToX returns the screen column of the given angle relative to the view direction, which must lie within
+// the field of view.
+func (r *Renderer) viewAngleToX(angle bam) int {
+	x := int(math.Ceil(r.centerX - math.Tan(angle.signedRadians())*r.projection))
+	return min(max(x, 0), r.Width)
+}
+
+// pointToAngle returns the angle from the viewer to the given map position.
+func (r *Renderer) pointToAngle(x float64, y float64) bam {
+	return bamFromRadians(math.Atan2(y-r.viewY, x-r.viewX))
+}
+
+func (r *Renderer) pointToDist(x float64, y float64) float64 {
+	return math.Hypot(x-r.viewX, y-r.viewY)
+}
+
+// Animate advances animated flats to the given game tic.
+func (r *Renderer) Animate(tic int) {
+	r.flats.Animate(tic)
+}
+
+// RenderPlayerView draws the given map as seen from the player's position into the framebuffer.
+func (r *Renderer) RenderPlayerView(currentMap *Map) {
+	if currentMap != r.currentMap {
+		r.currentMap = currentMap
+		r.skyTexture, _ = r.textures.Lookup(SkyTextureName(currentMap.Name))
+		r.sortThings()
+	}
+	r.frame++
+	r.viewX = PlayerX
+	r.viewY = PlayerY
+	r.viewAngle = bamFromDegrees(PlayerAngle)
+	playerSector := currentMap.Sectors[currentMap.SubSectors[currentMap.PointInSubSector(PlayerX, PlayerY)].sector]
+	r.viewZ = float64(playerSector.floorHeight) + PlayerViewHeight
+
+	r.Framebuffer.Clear(0)
+	r.clearClipSegs()
+	r.clearPlanes()
+	r.drawSegs = r.drawSegs[:0]
+	r.openings = r.openings[:0]
+	r.vissprites = r.vissprites[:0]
+
+	r.renderBSPNode(currentMap.rootNode())
+	r.drawPlanes()
+	r.drawMasked()
+}
+
+func (r *Renderer) clearClipSegs() {
+	r.solidSegs = append(r.solidSegs[:0], clipRange{math.MinInt32, -1}, clipRange{r.Width, math.MaxInt32})
+	for x := 0; x < r.Width; x++ {
+		r.ceilingClip[x] = -1
+		r.floorClip[x] = r.Height
+	}
+}
+
+// renderBSPNode renders the subsectors of the given node, the side the viewer is on first. The side behind is only
+// visited if its bounding box may be visible.
+func (r *Renderer) renderBSPNode(nodeId int16) {
+	if nodeId < 0 {
+		r.renderSubSector(subSectorIndex(nodeId))
+		return
+	}
+
+	node := r.currentMap.Nodes[nodeId]
+	side := node.pointOnSide(r.viewX, r.viewY)
+	r.renderBSPNode(node.child(side))
+	if r.checkBoundingBox(node.boundingBox(side ^ 1)) {
+		r.renderBSPNode(node.child(side ^ 1))
+	}
+}
+
+// boundingBoxCorners lists, for each position of the viewer relative to a bounding box (3x3 grid, row by row from the
+// top), the coordinates of the two corners spanning the box as seen from there: x1, y1, x2, y2 as indexes of top,
+// bottom, left and right.
+var boundingBoxCorners = [11][4]int{
+	{3, 0, 2, 1},
+	{3, 0, 2, 0},
+	{3, 1, 2, 0},
+	{},
+	{2, 0, 2, 1},
+	{},
+	{3, 1, 3, 0},
+	{},
+	{2, 0, 3, 1},
+	{2, 1, 3, 1},
+	{2, 1, 3, 0},
+}
+
+// checkBoundingBox reports whether some part of the bounding box may be visible: it has to lie within the field of
+// view and must not be covered by solid walls.
+func (r *Renderer) checkBoundingBox(data int64) bool {
+	top, bottom, left, right := decodeBoundingBox(data)
+	coordinates := [4]float64{float64(top), float64(bottom), float64(left), float64(right)}
+
+	boxX := 2
+	if r.viewX <= coordinates[2] {
+		boxX = 0
+	} else if r.viewX < coordinates[3] {
+		boxX = 1
+	}
+	boxY := 2
+	if r.viewY >= coordinates[0] {
+		boxY = 0
+	} else if r.viewY > coordinates[1] {
+		boxY = 1
+	}
+	boxPosition := boxY<<2 + boxX
+	if boxPosition == 5 {
+		return true // viewer is inside the box
+	}
+
+	corners := boundingBoxCorners[boxPosition]
+	angle1 := r.pointToAngle(coordinates[corners[0]], coordinates[corners[1]]) - r.viewAngle
+	angle2 := r.pointToAngle(coordinates[corners[2]], coordinates[corners[3]]) - r.viewAngle
+	span := angle1 - angle2
+	if span >= bamAngle180 {
+		return true // sitting on a line
+	}
+
+	x1, x2, ok := r.clipAnglesToView(angle1, angle2, span)
+	if !ok || x1 == x2 {
+		return false
+	}
+	x2--
+
+	i := 0
+	for r.solidSegs[i].last < x2 {
+		i++
+	}
+	return x1 < r.solidSegs[i].first || x2 > r.solidSegs[i].last
+}
+
+// clipAnglesToView clips the span between the given angles (relative to the view direction, angle1 left of angle2)
+// to the field of view and returns the screen columns it covers. It reports false if the span lies outside the view.
+func (r *Renderer) clipAnglesToView(angle1 bam, angle2 bam, span bam) (int, int, bool) {
+	if tspan := angle1 + r.clipAngle; tspan > 2*r.clipAngle {
+		if tspan-2*r.clipAngle >= span {
+			return 0, 0, false // totally off the left edge
+		}
+		angle1 = r.clipAngle
+	}
+	if tspan := r.clipAngle - angle2; tspan > 2*r.clipAngle {
+		if tspan-2*r.clipAngle >= span {
+			return 0, 0, false // totally off the right edge
+		}
+		angle2 = -r.clipAngle
+	}
+	return r.viewAngleToX(angle1), r.viewAngleToX(angle2), true
+}
+
+func (r *Renderer) renderSubSector(index int) {
+	subSector := r.currentMap.SubSectors[index]
+	r.frontSector = &r.currentMap.Sectors[subSector.sector]
+	sector := r.frontSector
+	r.floorPlane = nil
+	if float64(sector.floorHeight) < r.viewZ {
+		r.floorPlane = r.findPlane(sector.floorHeight, sector.nameOfFloorTexture, sector.lightLevel)
+	}
+	r.ceilingPlane = nil
+	if float64(sector.ceilingHeight) > r.viewZ || sector.nameOfCeilingTexture == SkyFlatName {
+		r.ceilingPlane = r.findPlane(sector.ceilingHeight, sector.nameOfCeilingTexture, sector.lightLevel)
+	}
+	r.addSprites(subSector.sector)
+	for i := 0; i < int(subSector.segCount); i++ {
+		r.addLine(&r.currentMap.Segs[int(subSector.firstSegNumber)+i])
+	}
+}
+
+// addLine clips the given seg to the field of view and passes the visible columns on to be drawn.
+func (r *Renderer) addLine(seg *Seg) {
+	v1 := r.currentMap.Vertexes[seg.startingVertexNumber]
+	v2 := r.currentMap.Vertexes[seg.endingVertexNumber]
+	angle1 := r.pointToAngle(float64(v1.XPosition), float64(v1.YPosition))
+	angle2 := r.pointToAngle(float64(v2.XPosition), float64(v2.YPosition))
+
+	span := angle1 - angle2
+	if span >= bamAngle180 {
+		return // back side
+	}
+
+	r.currentSeg = seg
+	r.rwAngle1 = angle1
+	x1, x2, ok := r.clipAnglesToView(angle1-r.viewAngle, angle2-r.viewAngle, span)
+	if !ok || x1 == x2 {
+		return // does not cross a pixel
+	}
+
+	if seg.backSector == NoSector {
+		r.backSector = nil
+		r.clipSolidWallSegment(x1, x2-1)
+		return
+	}
+
+	r.backSector = &r.currentMap.Sectors[seg.backSector]
+	front, back := r.frontSector, r.backSector
+	if back.ceilingHeight <= front.floorHeight || back.floorHeight >= front.ceilingHeight {
+		// closed door
+		r.clipSolidWallSegment(x1, x2-1)
+		return
+	}
+	if back.ceilingHeight == front.ceilingHeight && back.floorHeight == front.floorHeight &&
+		back.nameOfCeilingTexture == front.nameOfCeilingTexture &&
+		back.nameOfFloorTexture == front.nameOfFloorTexture && back.lightLevel == front.lightLevel &&
+		r.currentMap.Sidedefs[seg.sideDef].MiddleTexture == NoTexture {
+		// nothing to draw, such lines only trigger specials
+		return
+	}
+	r.clipPassWallSegment(x1, x2-1)
+}
+
+// clipSolidWallSegment draws the parts of the column range [first, last] not yet covered by solid walls and marks the
+// whole range as covered.
+func (r *Renderer) clipSolidWallSegment(first int, last int) {
+	// find the first range that touches the range (adjacent pixels are touching)
+	start := 0
+	for r.solidSegs[start].last < first-1 {
+		start++
+	}
+
+	if first < r.solidSegs[start].first {
+		if last < r.solidSegs[start].first-1 {
+			// post is entirely visible (above start), so insert a new range
+			r.storeWallRange(first, last)
+			r.solidSegs = append(r.solidSegs, clipRange{})
+			copy(r.solidSegs[start+1:], r.solidSegs[start:])
+			r.solidSegs[start] = clipRange{first, last}
+			return
+		}
+
+		// there is a fragment above start
+		r.storeWallRange(first, r.solidSegs[start].first-1)
+		r.solidSegs[start].first = first
+	}
+
+	// bottom contained in start?
+	if last <= r.solidSegs[start].last {
+		return
+	}
+
+	next := start
+	for last >= r.solidSegs[next+1].first-1 {
+		// there is a fragment between two ranges
+		r.storeWallRange(r.solidSegs[next].last+1, r.solidSegs[next+1].first-1)
+		next++
+		if last <= r.solidSegs[next].last {
+			// bottom is contained in next, adjust the clip size
+			last = r.solidSegs[next].last
+			break
+		}
+	}
+	if last > r.solidSegs[next].last {
+		// there is a fragment after next
+		r.storeWallRange(r.solidSegs[next].last+1, last)
+	}
+	r.solidSegs[start].last = last
+
+	// remove the ranges start now covers
+	if next != start {
+		r.solidSegs = append(r.solidSegs[:start+1], r.solidSegs[next+1:]...)
+	}
+}
+
+// clipPassWallSegment draws the parts of the column range [first, last] not yet covered by solid walls without
+// covering the range, as walls behind a two-sided line remain visible.
+func (r *Renderer) clipPassWallSegment(first int, last int) {
+	start := 0
+	for r.solidSegs[start].last < first-1 {
+		start++
+	}
+
+	if first < r.solidSegs[start].first {
+		if last < r.solidSegs[start].first-1 {
+			r.storeWallRange(first, last)
+			return
+		}
+		r.storeWallRange(first, r.solidSegs[start].first-1)
+	}
+
+	if last <= r.solidSegs[start].last {
+		return
+	}
+
+	for last >= r.solidSegs[start+1].first-1 {
+		r.storeWallRange(r.solidSegs[start].last+1, r.solidSegs[start+1].first-1)
+		start++
+		if last <= r.solidSegs[start].last {
+			return
+		}
+	}
+	r.storeWallRange(r.solidSegs[start].last+1, last)
+}
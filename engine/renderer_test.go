@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// TestRenderPlayerViewGolden renders the test map headlessly and compares the frames with the images in testdata.
+// After intended changes to the renderer, inspect the differences and run the test with -update.
+func TestRenderPlayerViewGolden(t *testing.T) {
+	wad := loadWad(t, "IWAD", testIwadLumps())
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+
+	views := []struct {
+		resolution Resolution
+		angle      float64
+	}{
+		{DefaultResolution, 0},
+		{DefaultResolution, 30},
+		{DefaultResolution, 180},
+		{Resolution{Width: 640, Height: 300, PixelAspect: OriginalPixelAspect}, 0},
+	}
+	for _, view := range views {
+		name := fmt.Sprintf("e1m1_%s_%03.0f", view.resolution, view.angle)
+		t.Run(name, func(t *testing.T) {
+			renderer, err := NewRenderer(wad, view.resolution)
+			if err != nil {
+				t.Fatalf("NewRenderer: %v", err)
+			}
+			SpawnPlayer(&m)
+			PlayerAngle = view.angle
+			renderer.RenderPlayerView(&m)
+			compareGolden(t, filepath.Join("testdata", name+".png"), renderer.Framebuffer)
+		})
+	}
+}
+
+// TestRenderNoTextureGolden renders the grate between the rooms with "-" as its upper and lower texture, which
+// leaves those sections open instead of drawing them untextured.
+func TestRenderNoTextureGolden(t *testing.T) {
+	lumps := testMapLumps("E1M1")
+	sidedefs := &lumps[3]
+	sidedefs.data = slices.Clone(sidedefs.data)
+	copy(sidedefs.data[3*SidedefsBlockSize+4:], append(name8(NoTexture), name8(NoTexture)...))
+	wad := loadWad(t, "IWAD", append(testGraphicsLumps(), lumps...))
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	renderer, err := NewRenderer(wad, DefaultResolution)
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+	SpawnPlayer(&m)
+	PlayerAngle = 0
+	renderer.RenderPlayerView(&m)
+	compareGolden(t, filepath.Join("testdata", "e1m1_no_texture.png"), renderer.Framebuffer)
+}
+
+func TestDrawMapGolden(t *testing.T) {
+	wad := loadWad(t, "IWAD", testIwadLumps())
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	palettes, err := wad.ReadPalettes()
+	if err != nil {
+		t.Fatalf("ReadPalettes: %v", err)
+	}
+	SpawnPlayer(&m)
+	frame := NewFramebuffer(DefaultResolution.Width, DefaultResolution.Height, &palettes[0])
+	DrawMap(frame, &m)
+	compareGolden(t, filepath.Join("testdata", "e1m1_automap.png"), frame)
+}
+
+func TestDrawMapWithoutNodes(t *testing.T) {
+	// a map of a single subsector has no nodes, its BSP tree is that subsector
+	lumps := testMapLumps("E1M1")
+	for i := range lumps {
+		if lumps[i].name == NodesLump {
+			lumps[i].data = nil
+		}
+	}
+	wad := loadWad(t, "IWAD", append(testGraphicsLumps(), lumps...))
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	palettes, err := wad.ReadPalettes()
+	if err != nil {
+		t.Fatalf("ReadPalettes: %v", err)
+	}
+	SpawnPlayer(&m)
+	DrawMap(NewFramebuffer(DefaultResolution.Width, DefaultResolution.Height, &palettes[0]), &m)
+}
+
+// compareGolden compares the pixels of the frame with the golden image at the given path, or writes the image when
+// the -update flag is given.
+func compareGolden(t *testing.T, path string, frame *Framebuffer) {
+	t.Helper()
+	if *update {
+		var buffer bytes.Buffer
+		if err := frame.EncodePNG(&buffer); err != nil {
+			t.Fatalf("EncodePNG: %v", err)
+		}
+		if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+			t.Fatalf("could not write golden image: %v", err)
+		}
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("could not read golden image, run with -update to create it: %v", err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("could not decode golden image: %v", err)
+	}
+	golden, ok := decoded.(*image.Paletted)
+	if !ok || golden.Rect.Dx() != frame.Width || golden.Rect.Dy() != frame.Height {
+		t.Fatalf("golden image is not a %dx%d paletted image", frame.Width, frame.Height)
+	}
+	differing := 0
+	for i, pixel := range frame.Pixels {
+		if golden.Pix[i] != pixel {
+			differing++
+		}
+	}
+	if differing > 0 {
+		t.Errorf("%d of %d pixels differ from %s", differing, len(frame.Pixels), path)
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// OriginalPixelAspect is the height of a pixel relative to its width when the original 320x200 screen is shown
+	// at 4:3. Textures and sprites were drawn for these tall pixels.
+	OriginalPixelAspect = 1.2
+
+	// originalProjection is the distance of the projection plane in pixels of the original 320x200 view, which
+	// scales and light diminishing were tuned for
+	originalProjection = 160
+)
+
+// ErrBadResolution is returned for resolutions that cannot be parsed or rendered.
+var ErrBadResolution = errors.New("bad resolution, expected WIDTHxHEIGHT")
+
+// Resolution is the size of the framebuffer the renderer draws into and the shape of its pixels on screen.
+type Resolution struct {
+	Width  int
+	Height int
+	// PixelAspect is the height of a pixel relative to its width on screen: OriginalPixelAspect to stretch the image
+	// like the original on a 4:3 display, 1 for square pixels.
+	PixelAspect float64
+}
+
+// DefaultResolution is the resolution of the original engine.
+var DefaultResolution = Resolution{Width: 320, Height: 200, PixelAspect: OriginalPixelAspect}
+
+// Resolutions are the resolutions that can be switched between while playing, the last two of them widescreen.
+var Resolutions = []Resolution{
+	DefaultResolution,
+	{Width: 640, Height: 400, PixelAspect: OriginalPixelAspect},
+	{Width: 1280, Height: 800, PixelAspect: OriginalPixelAspect},
+	{Width: 640, Height: 300, PixelAspect: OriginalPixelAspect},
+	{Width: 1280, Height: 600, PixelAspect: OriginalPixelAspect},
+}
+
+// ParseResolution parses a resolution given as WIDTHxHEIGHT, e.g. 640x400, with the original pixel aspect.
+func ParseResolution(s string) (Resolution, error) {
+	resolution := Resolution{PixelAspect: OriginalPixelAspect}
+	var rest string
+	if n, _ := fmt.Sscanf(s, "%dx%d%s", &resolution.Width, &resolution.Height, &rest); n != 2 {
+		return Resolution{}, fmt.Errorf("%w: %q", ErrBadResolution, s)
+	}
+	if err := resolution.validate(); err != nil {
+		return Resolution{}, err
+	}
+	return resolution, nil
+}
+
+func (r Resolution) validate() error {
+	if r.Width < 2 || r.Height < 2 || r.Width > 8192 || r.Height > 8192 || r.PixelAspect <= 0 {
+		return fmt.Errorf("%w: %dx%d", ErrBadResolution, r.Width, r.Height)
+	}
+	return nil
+}
+
+// DisplayAspect returns the width of the image on screen relative to its height.
+func (r Resolution) DisplayAspect() float64 {
+	return float64(r.Width) / (float64(r.Height) * r.PixelAspect)
+}
+
+func (r Resolution) String() string {
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseResolution(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Resolution
+		err  error
+	}{
+		{"320x200", DefaultResolution, nil},
+		{"640x300", Resolution{Width: 640, Height: 300, PixelAspect: OriginalPixelAspect}, nil},
+		{"640", Resolution{}, ErrBadResolution},
+		{"640-400", Resolution{}, ErrBadResolution},
+		{"640x", Resolution{}, ErrBadResolution},
+		{"0x200", Resolution{}, ErrBadResolution},
+		{"320x0", Resolution{}, ErrBadResolution},
+		{"-320x200", Resolution{}, ErrBadResolution},
+		{"320x-200", Resolution{}, ErrBadResolution},
+		{"widexhigh", Resolution{}, ErrBadResolution},
+		{"320x200px", Resolution{}, ErrBadResolution},
+		{"", Resolution{}, ErrBadResolution},
+	}
+	for _, test := range tests {
+		got, err := ParseResolution(test.s)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("ParseResolution(%q) = %v, %v, want %v, %v", test.s, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestSetResolution(t *testing.T) {
+	wad := loadWad(t, "IWAD", testIwadLumps())
+	renderer, err := NewRenderer(wad, DefaultResolution)
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+	if err := renderer.SetResolution(Resolution{Width: 1280, Height: 600, PixelAspect: OriginalPixelAspect}); err != nil {
+		t.Fatalf("SetResolution: %v", err)
+	}
+	if renderer.Width != 1280 || renderer.Height != 600 || len(renderer.Framebuffer.Pixels) != 1280*600 {
+		t.Errorf("got %dx%d with a framebuffer of %d pixels", renderer.Width, renderer.Height,
+			len(renderer.Framebuffer.Pixels))
+	}
+
+	for _, resolution := range []Resolution{
+		{Width: 0, Height: 200, PixelAspect: OriginalPixelAspect},
+		{Width: 320, Height: -200, PixelAspect: OriginalPixelAspect},
+		{Width: 320, Height: 200, PixelAspect: 0},
+	} {
+		if err := renderer.SetResolution(resolution); !errors.Is(err, ErrBadResolution) {
+			t.Errorf("SetResolution(%+v) error = %v, want %v", resolution, err, ErrBadResolution)
+		}
+	}
+	if renderer.Width != 1280 || renderer.Height != 600 {
+		t.Errorf("rejected resolution changed the renderer to %dx%d", renderer.Width, renderer.Height)
+	}
+	if _, err := NewRenderer(wad, Resolution{Width: 320, Height: 1}); !errors.Is(err, ErrBadResolution) {
+		t.Errorf("NewRenderer error = %v, want %v", err, ErrBadResolution)
+	}
+}
+
+func TestRenderOddResolution(t *testing.T) {
+	wad := loadWad(t, "IWAD", testIwadLumps())
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	renderer, err := NewRenderer(wad, Resolution{Width: 321, Height: 201, PixelAspect: OriginalPixelAspect})
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+	if renderer.centerX != 160 || renderer.centerY != 100 {
+		t.Errorf("centre (%v, %v), want (160, 100)", renderer.centerX, renderer.centerY)
+	}
+	// no row of a plane may lie at the height of the eyes, where its distance would be infinite
+	for y := 0; y < renderer.Height; y++ {
+		if dy := math.Abs(float64(y) - renderer.centerY + 0.5); dy < 0.5 {
+			t.Errorf("row %d lies %v pixels from the centre", y, dy)
+		}
+	}
+	SpawnPlayer(&m)
+	PlayerAngle = 0
+	renderer.RenderPlayerView(&m)
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// ScreenshotPrefix starts the file names of screenshots, which are numbered from DOOM00.png to DOOM99.png
+	ScreenshotPrefix = "DOOM"
+	maxScreenshots   = 100
+)
+
+// ErrNoScreenshotName is returned when all screenshot file names are taken.
+var ErrNoScreenshotName = errors.New("no unused screenshot file name")
+
+// EncodePNG writes the framebuffer as a paletted PNG image.
+func (f *Framebuffer) EncodePNG(w io.Writer) error {
+	return png.Encode(w, f.Image())
+}
+
+// SaveScreenshot writes the framebuffer to the first unused numbered PNG file in the given directory and returns its
+// path.
+func SaveScreenshot(frame *Framebuffer, dir string) (string, error) {
+	for i := 0; i < maxScreenshots; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%s%02d.png", ScreenshotPrefix, i))
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := errors.Join(frame.EncodePNG(file), file.Close()); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return "", ErrNoScreenshotName
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveScreenshot(t *testing.T) {
+	var palette Palette
+	for i := range palette {
+		palette[i].R = byte(i)
+	}
+	frame := NewFramebuffer(4, 2, &palette)
+	frame.Clear(7)
+	frame.SetPixel(1, 1, 200)
+
+	dir := t.TempDir()
+	for _, want := range []string{"DOOM00.png", "DOOM01.png"} {
+		path, err := SaveScreenshot(frame, dir)
+		if err != nil {
+			t.Fatalf("SaveScreenshot: %v", err)
+		}
+		if path != filepath.Join(dir, want) {
+			t.Errorf("saved to %s, want %s", path, want)
+		}
+	}
+
+	file, err := os.Open(filepath.Join(dir, "DOOM01.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("could not decode screenshot: %v", err)
+	}
+	img, ok := decoded.(*image.Paletted)
+	if !ok || img.ColorIndexAt(0, 0) != 7 || img.ColorIndexAt(1, 1) != 200 {
+		t.Errorf("screenshot does not hold the framebuffer pixels")
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// skyTextureMid is the sky texture row at the height of the viewer's eyes
+	skyTextureMid = 100
+
+	// skyAngleShift maps view angles to sky texture columns: 1024 columns for a full turn
+	skyAngleShift = 22
+)
+
+// SkyTextureName returns the name of the sky texture shown on the given map: SKY1 to SKY4 for the episodes of Doom,
+// and SKY1 up to MAP11, SKY2 up to MAP20 and SKY3 from then on in Doom II.
+func SkyTextureName(mapName string) string {
+	mapName = strings.ToUpper(mapName)
+	if len(mapName) == 4 && mapName[0] == 'E' && mapName[2] == 'M' && mapName[1] >= '1' && mapName[1] <= '4' {
+		return "SKY" + mapName[1:2]
+	}
+	if number, err := strconv.Atoi(strings.TrimPrefix(mapName, "MAP")); err == nil && strings.HasPrefix(mapName, "MAP") {
+		switch {
+		case number < 12:
+			return "SKY1"
+		case number < 21:
+			return "SKY2"
+		default:
+			return "SKY3"
+		}
+	}
+	return "SKY1"
+}
+
+// drawSky draws the columns of a sky visplane. The sky does not move with the viewer's position and is not affected
+// by light, it turns with the view angle only.
+func (r *Renderer) drawSky(plane *visplane) {
+	if r.skyTexture == nil {
+		return
+	}
+	texture := &wallTexture{texture: r.skyTexture, textureMid: skyTextureMid}
+	step := originalProjection / r.yProjection
+	for x := plane.minX; x <= plane.maxX; x++ {
+		yl, yh := plane.top[x+1], plane.bottom[x+1]
+		if yl > yh {
+			continue
+		}
+		angle := r.viewAngle + r.xToViewAngle[x]
+		column := wallColumn{x: x, textureColumn: int(angle >> skyAngleShift), step: step, colormap: &r.colormaps[0]}
+		r.drawWallColumn(column, yl, yh, texture)
+	}
+}
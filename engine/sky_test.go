@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestSkyTextureName(t *testing.T) {
+	tests := []struct {
+		mapName string
+		want    string
+	}{
+		{"E1M1", "SKY1"},
+		{"e2m5", "SKY2"},
+		{"E3M9", "SKY3"},
+		{"E4M1", "SKY4"},
+		{"E5M1", "SKY1"},
+		{"MAP01", "SKY1"},
+		{"MAP11", "SKY1"},
+		{"MAP12", "SKY2"},
+		{"MAP20", "SKY2"},
+		{"MAP21", "SKY3"},
+		{"MAP32", "SKY3"},
+		{"MYMAP", "SKY1"},
+	}
+	for _, test := range tests {
+		if got := SkyTextureName(test.mapName); got != test.want {
+			t.Errorf("SkyTextureName(%q) = %q, want %q", test.mapName, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sprites see: https://doomwiki.org/wiki/Sprite
+const (
+	SpriteStartMarker = "S_START"
+	SpriteEndMarker   = "S_END"
+
+	// NumRotations is the number of directions a rotating sprite frame is drawn from
+	NumRotations = 8
+
+	maxSpriteFrames = 29
+)
+
+// ErrIncompleteSpriteFrame is returned for sprite frames that cannot be drawn from all directions.
+var ErrIncompleteSpriteFrame = errors.New("sprite frame is missing rotations")
+
+// SpriteFrame is a frame of a sprite, seen from up to eight directions. Rotation 0 faces the viewer, the following
+// ones turn counter-clockwise in steps of 45 degrees.
+type SpriteFrame struct {
+	Rotate   bool // false if the frame looks the same from all directions
+	Pictures [NumRotations]*Picture
+	Flip     [NumRotations]bool // whether the picture is drawn mirrored
+}
+
+// Sprites is the table of all sprites, looked up by their four letter names.
+type Sprites struct {
+	frames map[string][]SpriteFrame
+}
+
+// ReadSprites builds the sprite table from the lumps between S_START and S_END. Lump names consist of the sprite name,
+// a frame letter and a rotation digit, optionally followed by a second frame and rotation the picture is used for
+// mirrored. Later lumps replace earlier ones, so PWADs merged into the namespace override single rotations.
+func (w *Wad) ReadSprites() (*Sprites, error) {
+	start, ok := w.ReadLumpIndexForName(SpriteStartMarker)
+	if !ok {
+		return nil, &LumpError{Lump: SpriteStartMarker, Offset: 0, Err: ErrLumpNotFound}
+	}
+	end, ok := w.FindLumpInRange(SpriteEndMarker, start, w.NumLumps())
+	if !ok {
+		return nil, &LumpError{Lump: SpriteEndMarker, Offset: 0, Err: ErrLumpNotFound}
+	}
+
+	sprites := &Sprites{frames: make(map[string][]SpriteFrame)}
+	frameLumps := make(map[string]Directory) // last lump installed for each sprite name and frame letter
+	for i := start + 1; i < end; i++ {
+		directory := w.ReadDirectoryForLumpIndex(i)
+		if directory.size == 0 || len(directory.name) < 6 {
+			continue // sub-markers like S1_START
+		}
+		picture, err := w.readPictureForLumpIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		name := directory.name[:4]
+		sprites.install(name, directory.name[4], directory.name[5], picture, false)
+		frameLumps[directory.name[:5]] = directory
+		if len(directory.name) >= 8 {
+			sprites.install(name, directory.name[6], directory.name[7], picture, true)
+			frameLumps[name+directory.name[6:7]] = directory
+		}
+	}
+	if err := sprites.checkFrames(frameLumps); err != nil {
+		return nil, err
+	}
+	return sprites, nil
+}
+
+// checkFrames reports the first frame that lacks a picture for one of its rotations, or that has no pictures at all
+// although later frames of the sprite do. Like the original engine, such sprites are refused rather than drawn with
+// holes.
+func (s *Sprites) checkFrames(frameLumps map[string]Directory) error {
+	names := make([]string, 0, len(s.frames))
+	for name := range s.frames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for frame, spriteFrame := range s.frames[name] {
+			lump := fmt.Sprintf("%s%c", name, 'A'+frame)
+			directory, ok := frameLumps[lump]
+			if !ok {
+				return &LumpError{Lump: lump, Offset: 0,
+					Err: fmt.Errorf("%w: no pictures found", ErrIncompleteSpriteFrame)}
+			}
+			for rotation, picture := range spriteFrame.Pictures {
+				if picture == nil {
+					return &LumpError{Lump: directory.name, Offset: directory.filepos,
+						Err: fmt.Errorf("%w: rotation %d", ErrIncompleteSpriteFrame, rotation+1)}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// install sets the picture of a frame and rotation given as characters of a lump name. Invalid ones are ignored.
+func (s *Sprites) install(name string, frameChar byte, rotationChar byte, picture *Picture, flip bool) {
+	frame := int(frameChar) - 'A'
+	rotation := int(rotationChar) - '0'
+	if frame < 0 || frame >= maxSpriteFrames || rotation < 0 || rotation > NumRotations {
+		return
+	}
+	frames := s.frames[name]
+	for len(frames) <= frame {
+		frames = append(frames, SpriteFrame{})
+	}
+	if rotation == 0 {
+		frames[frame].Rotate = false
+		for i := range frames[frame].Pictures {
+			frames[frame].Pictures[i] = picture
+			frames[frame].Flip[i] = flip
+		}
+	} else {
+		frames[frame].Rotate = true
+		frames[frame].Pictures[rotation-1] = picture
+		frames[frame].Flip[rotation-1] = flip
+	}
+	s.frames[name] = frames
+}
+
+// Frame returns the given frame (0 for 'A') of the named sprite.
+func (s *Sprites) Frame(name string, frame int) (*SpriteFrame, bool) {
+	frames := s.frames[strings.ToUpper(name)]
+	if frame < 0 || frame >= len(frames) || frames[frame].Pictures[0] == nil {
+		return nil, false
+	}
+	return &frames[frame], true
+}
+
+// thingSprite is the sprite frame a thing is shown with when the map starts.
+type thingSprite struct {
+	sprite string
+	frame  byte
+	bright bool // drawn at full brightness regardless of light
+	hangs  bool // hangs from the ceiling
+}
+
+// thingSprites maps the thing types placed in maps to their sprites. Player starts and the invisible things of Doom II
+// are left out.
+var thingSprites = map[int16]thingSprite{
+	// monsters
+	3004: {sprite: "POSS", frame: 'A'},
+	9:    {sprite: "SPOS", frame: 'A'},
+	65:   {sprite: "CPOS", frame: 'A'},
+	3001: {sprite: "TROO", frame: 'A'},
+	3002: {sprite: "SARG", frame: 'A'},
+	58:   {sprite: "SARG", frame: 'A'},
+	3006: {sprite: "SKUL", frame: 'A', bright: true},
+	3005: {sprite: "HEAD", frame: 'A'},
+	69:   {sprite: "BOS2", frame: 'A'},
+	3003: {sprite: "BOSS", frame: 'A'},
+	68:   {sprite: "BSPI", frame: 'A'},
+	71:   {sprite: "PAIN", frame: 'A'},
+	66:   {sprite: "SKEL", frame: 'A'},
+	67:   {sprite: "FATT", frame: 'A'},
+	64:   {sprite: "VILE", frame: 'A'},
+	7:    {sprite: "SPID", frame: 'A'},
+	16:   {sprite: "CYBR", frame: 'A'},
+	84:   {sprite: "SSWV", frame: 'A'},
+	72:   {sprite: "KEEN", frame: 'A', hangs: true},
+	88:   {sprite: "BBRN", frame: 'A'},
+
+	// weapons
+	2005: {sprite: "CSAW", frame: 'A'},
+	2001: {sprite: "SHOT", frame: 'A'},
+	82:   {sprite: "SGN2", frame: 'A'},
+	2002: {sprite: "MGUN", frame: 'A'},
+	2003: {sprite: "LAUN", frame: 'A'},
+	2004: {sprite: "PLAS", frame: 'A'},
+	2006: {sprite: "BFUG", frame: 'A'},
+
+	// ammunition
+	2007: {sprite: "CLIP", frame: 'A'},
+	2048: {sprite: "AMMO", frame: 'A'},
+	2008: {sprite: "SHEL", frame: 'A'},
+	2049: {sprite: "SBOX", frame: 'A'},
+	2010: {sprite: "ROCK", frame: 'A'},
+	2046: {sprite: "BROK", frame: 'A'},
+	2047: {sprite: "CELL", frame: 'A'},
+	17:   {sprite: "CELP", frame: 'A'},
+	8:    {sprite: "BPAK", frame: 'A'},
+
+	// health, armor and powerups
+	2011: {sprite: "STIM", frame: 'A'},
+	2012: {sprite: "MEDI", frame: 'A'},
+	2014: {sprite: "BON1", frame: 'A'},
+	2015: {sprite: "BON2", frame: 'A'},
+	2018: {sprite: "ARM1", frame: 'A'},
+	2019: {sprite: "ARM2", frame: 'A'},
+	2013: {sprite: "SOUL", frame: 'A', bright: true},
+	83:   {sprite: "MEGA", frame: 'A', bright: true},
+	2022: {sprite: "PINV", frame: 'A', bright: true},
+	2023: {sprite: "PSTR", frame: 'A', bright: true},
+	2024: {sprite: "PINS", frame: 'A', bright: true},
+	2025: {sprite: "SUIT", frame: 'A', bright: true},
+	2026: {sprite: "PMAP", frame: 'A', bright: true},
+	2045: {sprite: "PVIS", frame: 'A', bright: true},
+
+	// keys
+	5:  {sprite: "BKEY", frame: 'A'},
+	6:  {sprite: "YKEY", frame: 'A'},
+	13: {sprite: "RKEY", frame: 'A'},
+	40: {sprite: "BSKU", frame: 'A'},
+	39: {sprite: "YSKU", frame: 'A'},
+	38: {sprite: "RSKU", frame: 'A'},
+
+	// obstacles and decorations
+	2035: {sprite: "BAR1", frame: 'A'},
+	70:   {sprite: "FCAN", frame: 'A', bright: true},
+	43:   {sprite: "TRE1", frame: 'A'},
+	47:   {sprite: "SMIT", frame: 'A'},
+	54:   {sprite: "TRE2", frame: 'A'},
+	2028: {sprite: "COLU", frame: 'A', bright: true},
+	85:   {sprite: "TLMP", frame: 'A', bright: true},
+	86:   {sprite: "TLP2", frame: 'A', bright: true},
+	34:   {sprite: "CAND", frame: 'A', bright: true},
+	35:   {sprite: "CBRA", frame: 'A', bright: true},
+	44:   {sprite: "TBLU", frame: 'A', bright: true},
+	45:   {sprite: "TGRN", frame: 'A', bright: true},
+	46:   {sprite: "TRED", frame: 'A', bright: true},
+	55:   {sprite: "SMBT", frame: 'A', bright: true},
+	56:   {sprite: "SMGT", frame: 'A', bright: true},
+	57:   {sprite: "SMRT", frame: 'A', bright: true},
+	48:   {sprite: "ELEC", frame: 'A'},
+	30:   {sprite: "COL1", frame: 'A'},
+	31:   {sprite: "COL2", frame: 'A'},
+	32:   {sprite: "COL3", frame: 'A'},
+	33:   {sprite: "COL4", frame: 'A'},
+	36:   {sprite: "COL5", frame: 'A'},
+	37:   {sprite: "COL6", frame: 'A'},
+	41:   {sprite: "CEYE", frame: 'A', bright: true},
+	42:   {sprite: "FSKU", frame: 'A', bright: true},
+	25:   {sprite: "POL1", frame: 'A'},
+	26:   {sprite: "POL6", frame: 'A'},
+	27:   {sprite: "POL4", frame: 'A'},
+	28:   {sprite: "POL2", frame: 'A'},
+	29:   {sprite: "POL3", frame: 'A', bright: true},
+	24:   {sprite: "POL5", frame: 'A'},
+	49:   {sprite: "GOR1", frame: 'A', hangs: true},
+	50:   {sprite: "GOR2", frame: 'A', hangs: true},
+	51:   {sprite: "GOR3", frame: 'A', hangs: true},
+	52:   {sprite: "GOR4", frame: 'A', hangs: true},
+	53:   {sprite: "GOR5", frame: 'A', hangs: true},
+	59:   {sprite: "GOR2", frame: 'A', hangs: true},
+	60:   {sprite: "GOR4", frame: 'A', hangs: true},
+	61:   {sprite: "GOR3", frame: 'A', hangs: true},
+	62:   {sprite: "GOR5", frame: 'A', hangs: true},
+	63:   {sprite: "GOR1", frame: 'A', hangs: true},
+	73:   {sprite: "HDB1", frame: 'A', hangs: true},
+	74:   {sprite: "HDB2", frame: 'A', hangs: true},
+	75:   {sprite: "HDB3", frame: 'A', hangs: true},
+	76:   {sprite: "HDB4", frame: 'A', hangs: true},
+	77:   {sprite: "HDB5", frame: 'A', hangs: true},
+	78:   {sprite: "HDB6", frame: 'A', hangs: true},
+	79:   {sprite: "POB1", frame: 'A'},
+	80:   {sprite: "POB2", frame: 'A'},
+	81:   {sprite: "BRS1", frame: 'A'},
+
+	// corpses
+	15: {sprite: "PLAY", frame: 'N'},
+	10: {sprite: "PLAY", frame: 'W'},
+	12: {sprite: "PLAY", frame: 'W'},
+	18: {sprite: "POSS", frame: 'L'},
+	19: {sprite: "SPOS", frame: 'L'},
+	20: {sprite: "TROO", frame: 'M'},
+	21: {sprite: "SARG", frame: 'N'},
+	22: {sprite: "HEAD", frame: 'L'},
+}
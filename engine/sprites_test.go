@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestReadSprites(t *testing.T) {
+	picture := func(width int) []byte {
+		return encodePicture(width, 4, 0, 4, func(x int, y int) int { return 1 })
+	}
+	wad := loadWad(t, "IWAD", []testLump{
+		{name: SpriteStartMarker},
+		{name: "BON1A0", data: picture(2)},
+		{name: "TROOA1", data: picture(1)},
+		{name: "TROOA2A8", data: picture(2)},
+		{name: "TROOA3A7", data: picture(3)},
+		{name: "TROOA4A6", data: picture(4)},
+		{name: "TROOA5", data: picture(5)},
+		{name: SpriteEndMarker},
+		{name: "BON1A0", data: picture(9)}, // a graphic of the same name outside the namespace
+	})
+	sprites, err := wad.ReadSprites()
+	if err != nil {
+		t.Fatalf("ReadSprites: %v", err)
+	}
+
+	bonus, ok := sprites.Frame("BON1", 0)
+	if !ok || bonus.Rotate || bonus.Pictures[5].Width != 2 {
+		t.Errorf("BON1A0 is not the picture from the sprite namespace")
+	}
+	imp, ok := sprites.Frame("troo", 0)
+	if !ok || !imp.Rotate {
+		t.Fatalf("TROO frame A not found or not rotating")
+	}
+	for rotation, want := range []struct {
+		width int
+		flip  bool
+	}{{1, false}, {2, false}, {3, false}, {4, false}, {5, false}, {4, true}, {3, true}, {2, true}} {
+		if imp.Pictures[rotation].Width != want.width || imp.Flip[rotation] != want.flip {
+			t.Errorf("rotation %d has width %d, flipped %v, want %d, %v", rotation+1, imp.Pictures[rotation].Width,
+				imp.Flip[rotation], want.width, want.flip)
+		}
+	}
+	if _, ok := sprites.Frame("TROO", 1); ok {
+		t.Errorf("TROO frame B found")
+	}
+}
+
+func TestReadSpritesIncompleteFrames(t *testing.T) {
+	picture := encodePicture(1, 1, 0, 1, func(x int, y int) int { return 1 })
+	imp := []string{"TROOA1", "TROOA2A8", "TROOA3A7", "TROOA4A6", "TROOA5"}
+	tests := []struct {
+		name  string
+		lumps []string
+		lump  string
+	}{
+		{"missing rotation", slices.Delete(slices.Clone(imp), 2, 3), "TROOA5"},
+		{"missing mirrored rotation", []string{"TROOA1", "TROOA2A8", "TROOA3", "TROOA4A6", "TROOA5"}, "TROOA5"},
+		{"missing frame", []string{"BON1A0", "BON1C0"}, "BON1B"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lumps := []testLump{{name: SpriteStartMarker}}
+			for _, name := range test.lumps {
+				lumps = append(lumps, testLump{name: name, data: picture})
+			}
+			lumps = append(lumps, testLump{name: SpriteEndMarker})
+			_, err := loadWad(t, "IWAD", lumps).ReadSprites()
+			if !errors.Is(err, ErrIncompleteSpriteFrame) {
+				t.Fatalf("ReadSprites error = %v, want %v", err, ErrIncompleteSpriteFrame)
+			}
+			var lumpError *LumpError
+			if !errors.As(err, &lumpError) || lumpError.Lump != test.lump {
+				t.Errorf("error %v does not name %s", err, test.lump)
+			}
+		})
+	}
+}
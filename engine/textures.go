@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+)
+
+// Texture lumps see: https://doomwiki.org/wiki/TEXTURE1_and_TEXTURE2 and https://doomwiki.org/wiki/PNAMES
+const (
+	PnamesLump   = "PNAMES"
+	Texture1Lump = "TEXTURE1"
+	Texture2Lump = "TEXTURE2"
+
+	// NoTexture is the texture name of sidedef sections that are not drawn
+	NoTexture = "-"
+
+	textureHeaderSize  = 22
+	texturePatchSize   = 10
+	patchNameBlockSize = 8
+)
+
+// Texture is a wall texture composed of one or more patches.
+//
+// Like in the original engine, a column covered by a single patch keeps that patch's transparent parts and can be
+// used as a masked middle texture, while columns covered by several patches are composited into solid columns. Only
+// the largest power of two not above the width is used for horizontal wrapping, so a 72 wide texture repeats after 64
+// columns.
+type Texture struct {
+	Name      string
+	Width     int
+	Height    int
+	widthMask int
+	pixels    []byte   // composited columns, one after another
+	posts     [][]Post // posts of each column, for drawing masked textures
+}
+
+// Textures is the table of all wall textures, looked up by the names used in sidedefs.
+type Textures struct {
+	textures []*Texture
+	byName   map[string]int
+}
+
+// texturePatch places a patch in a texture
+type texturePatch struct {
+	originX int
+	originY int
+	picture *Picture
+}
+
+// ReadTextures builds the texture table from TEXTURE1, the optional TEXTURE2 (not present in the shareware IWAD) and
+// the PNAMES patch list.
+func (w *Wad) ReadTextures() (*Textures, error) {
+	patches, err := w.readPatchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	textures := &Textures{byName: make(map[string]int)}
+	for _, lumpName := range []string{Texture1Lump, Texture2Lump} {
+		index, ok := w.ReadLumpIndexForName(lumpName)
+		if !ok {
+			if lumpName == Texture1Lump {
+				return nil, &LumpError{Lump: lumpName, Offset: 0, Err: ErrLumpNotFound}
+			}
+			continue
+		}
+		directory := w.ReadDirectoryForLumpIndex(index)
+		data, err := w.ReadLumpData(directory)
+		if err != nil {
+			return nil, err
+		}
+		if err := textures.readTextureLump(w, directory, data, patches); err != nil {
+			return nil, err
+		}
+	}
+	return textures, nil
+}
+
+// readPatchNames returns the patch names listed in PNAMES. Patches are only looked up once a texture uses them, some
+// WADs list patches that do not exist.
+func (w *Wad) readPatchNames() ([]string, error) {
+	directory, data, err := w.readLumpRecords(PnamesLump, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	}
+	count := int(readInt[int32](data[0:4]))
+	if count < 0 || len(data) < 4+count*patchNameBlockSize {
+		return nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = strings.ToUpper(readString(data[4+i*patchNameBlockSize : 4+(i+1)*patchNameBlockSize]))
+	}
+	return names, nil
+}
+
+func (t *Textures) readTextureLump(w *Wad, directory Directory, data []byte, patchNames []string) error {
+	shortLump := &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrShortLump}
+	if len(data) < 4 {
+		return shortLump
+	}
+	count := int(readInt[int32](data[0:4]))
+	if count < 0 || len(data) < 4+4*count {
+		return shortLump
+	}
+
+	for i := 0; i < count; i++ {
+		offset := int(readInt[int32](data[4+4*i : 8+4*i]))
+		if offset < 0 || offset+textureHeaderSize > len(data) {
+			return shortLump
+		}
+		entry := data[offset:]
+		texture := &Texture{
+			Name:   strings.ToUpper(readString(entry[0:8])),
+			Width:  int(readInt[int16](entry[12:14])),
+			Height: int(readInt[int16](entry[14:16])),
+		}
+		patchCount := int(readInt[int16](entry[20:22]))
+		if texture.Width <= 0 || texture.Height <= 0 || patchCount < 0 ||
+			offset+textureHeaderSize+patchCount*texturePatchSize > len(data) {
+			return shortLump
+		}
+
+		patches := make([]texturePatch, 0, patchCount)
+		for p := 0; p < patchCount; p++ {
+			patchEntry := entry[textureHeaderSize+p*texturePatchSize:]
+			patchNumber := int(readInt[int16](patchEntry[4:6]))
+			if patchNumber < 0 || patchNumber >= len(patchNames) {
+				return &LumpError{Lump: directory.name, Offset: directory.filepos + int32(offset), Err: ErrIndexOutOfRange}
+			}
+			picture, err := w.ReadPicture(patchNames[patchNumber])
+			if errors.Is(err, ErrLumpNotFound) {
+				continue // a missing patch leaves its part of the texture empty
+			}
+			if err != nil {
+				return err
+			}
+			patches = append(patches, texturePatch{
+				originX: int(readInt[int16](patchEntry[0:2])),
+				originY: int(readInt[int16](patchEntry[2:4])),
+				picture: picture,
+			})
+		}
+		texture.compose(patches)
+
+		// like in the original engine, the first definition of a name is used, searching TEXTURE1 before TEXTURE2
+		if _, ok := t.byName[texture.Name]; !ok {
+			t.byName[texture.Name] = len(t.textures)
+		}
+		t.textures = append(t.textures, texture)
+	}
+	return nil
+}
+
+// compose draws the patches into the texture columns.
+func (t *Texture) compose(patches []texturePatch) {
+	t.widthMask = 1
+	for t.widthMask*2 <= t.Width {
+		t.widthMask *= 2
+	}
+	t.widthMask--
+
+	t.pixels = make([]byte, t.Width*t.Height)
+	t.posts = make([][]Post, t.Width)
+	patchCount := make([]int, t.Width)
+	for _, patch := range patches {
+		x1 := max(patch.originX, 0)
+		x2 := min(patch.originX+patch.picture.Width, t.Width)
+		for x := x1; x < x2; x++ {
+			patchCount[x]++
+			column := t.pixels[x*t.Height : (x+1)*t.Height]
+			for _, post := range patch.picture.Column(x - patch.originX) {
+				position := patch.originY + post.TopDelta
+				pixels := post.Pixels
+				// the original engine clips posts above the texture without skipping their first pixels, which
+				// moves them down to the top edge
+				if position < 0 {
+					pixels = pixels[:max(len(pixels)+position, 0)]
+					position = 0
+				}
+				copy(column[min(position, t.Height):], pixels)
+				t.posts[x] = append(t.posts[x], Post{TopDelta: position, Pixels: pixels})
+			}
+		}
+	}
+
+	// columns covered by several patches lose their transparency
+	for x, count := range patchCount {
+		if count > 1 {
+			t.posts[x] = []Post{{TopDelta: 0, Pixels: t.pixels[x*t.Height : (x+1)*t.Height]}}
+		}
+	}
+}
+
+// Lookup returns the texture with the given name. The name "-" (NoTexture) is never found.
+func (t *Textures) Lookup(name string) (*Texture, bool) {
+	index, ok := t.Index(name)
+	if !ok {
+		return nil, false
+	}
+	return t.textures[index], true
+}
+
+// Index returns the position of the named texture in the table. Animated textures cycle through consecutive entries.
+func (t *Textures) Index(name string) (int, bool) {
+	if name == NoTexture || name == "" {
+		return -1, false
+	}
+	index, ok := t.byName[strings.ToUpper(name)]
+	return index, ok
+}
+
+// Texture returns the texture at the given position in the table.
+func (t *Textures) Texture(index int) *Texture {
+	return t.textures[index]
+}
+
+// Len returns the number of textures in the table.
+func (t *Textures) Len() int {
+	return len(t.textures)
+}
+
+// Column returns the composited pixels of the column at x, wrapping x like the original engine.
+func (t *Texture) Column(x int) []byte {
+	x &= t.widthMask
+	return t.pixels[x*t.Height : (x+1)*t.Height]
+}
+
+// MaskedColumn returns the posts of the column at x for drawing the texture with transparency.
+func (t *Texture) MaskedColumn(x int) []Post {
+	return t.posts[x&t.widthMask]
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+// testTexture defines an 8x8 texture made of a single patch.
+type testTexture struct {
+	name  string
+	patch int16
+}
+
+// textureLump returns a TEXTURE1/TEXTURE2 lump with the given textures.
+func textureLump(textures ...testTexture) []byte {
+	data := le(int32(len(textures)))
+	for i := range textures {
+		data = append(data, le(int32(4+4*len(textures)+i*(textureHeaderSize+texturePatchSize)))...)
+	}
+	for _, texture := range textures {
+		data = append(data, name8(texture.name)...)
+		data = append(data, le(int32(0), int16(8), int16(8), int32(0), int16(1))...)
+		data = append(data, le(int16(0), int16(0), texture.patch, int16(1), int16(0))...)
+	}
+	return data
+}
+
+// testPnames returns a PNAMES lump with the given patch names.
+func testPnames(names ...string) []byte {
+	data := le(int32(len(names)))
+	for _, name := range names {
+		data = append(data, name8(name)...)
+	}
+	return data
+}
+
+func TestReadTextures(t *testing.T) {
+	patch := func(color int) []byte {
+		return encodePicture(8, 8, 0, 0, func(x int, y int) int { return color })
+	}
+	grate := encodePicture(8, 8, 0, 0, func(x int, y int) int {
+		if y%2 != 0 {
+			return -1
+		}
+		return 50
+	})
+	wad := loadWad(t, "IWAD", []testLump{
+		{name: PnamesLump, data: testPnames("RED", "BLUE", "GRATE", "NOPATCH")},
+		{name: Texture1Lump, data: textureLump(
+			testTexture{"SHARED", 0}, testTexture{"FIRST", 0}, testTexture{"GRATE", 2})},
+		{name: Texture2Lump, data: textureLump(
+			testTexture{"SHARED", 1}, testTexture{"SECOND", 1}, testTexture{"HOLE", 3})},
+		{name: "RED", data: patch(176)},
+		{name: "BLUE", data: patch(200)},
+		{name: "GRATE", data: grate},
+	})
+
+	textures, err := wad.ReadTextures()
+	if err != nil {
+		t.Fatalf("ReadTextures: %v", err)
+	}
+	if textures.Len() != 6 { // the duplicate keeps its texture number like in the original engine
+		t.Errorf("got %d textures, want 6", textures.Len())
+	}
+	shared, ok := textures.Lookup("shared")
+	if !ok || shared.Column(0)[0] != 176 {
+		t.Errorf("SHARED is not the definition from TEXTURE1")
+	}
+	first, ok := textures.Lookup("first")
+	if !ok || first.Width != 8 || first.Height != 8 || first.Column(3)[5] != 176 {
+		t.Errorf("FIRST not composed from RED")
+	}
+	if second, ok := textures.Lookup("SECOND"); !ok || second.Column(7)[7] != 200 {
+		t.Errorf("SECOND of TEXTURE2 not composed from BLUE")
+	}
+	grateTexture, ok := textures.Lookup("GRATE")
+	if !ok || len(grateTexture.MaskedColumn(0)) != 4 || grateTexture.MaskedColumn(0)[1].TopDelta != 2 {
+		t.Errorf("GRATE does not keep the posts of its patch")
+	}
+	hole, ok := textures.Lookup("HOLE")
+	if !ok || hole.Column(0)[0] != 0 || len(hole.MaskedColumn(0)) != 0 {
+		t.Errorf("HOLE with a missing patch is not an empty texture")
+	}
+	if index, ok := textures.Index("SECOND"); !ok || textures.Texture(index).Name != "SECOND" {
+		t.Errorf("Index(SECOND) = %d, %v", index, ok)
+	}
+	if _, ok := textures.Lookup(NoTexture); ok {
+		t.Errorf("%q found", NoTexture)
+	}
+}
+
+func TestReadTexturesErrors(t *testing.T) {
+	tests := map[string]struct {
+		texture1 []byte
+		want     error
+	}{
+		"short count":         {[]byte{1, 0}, ErrShortLump},
+		"short offsets":       {le(int32(2), int32(12)), ErrShortLump},
+		"short definition":    {textureLump(testTexture{"WALL", 0})[:20], ErrShortLump},
+		"patch out of PNAMES": {textureLump(testTexture{"WALL", 5}), ErrIndexOutOfRange},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			wad := loadWad(t, "IWAD", []testLump{
+				{name: PnamesLump, data: testPnames("RED")},
+				{name: Texture1Lump, data: test.texture1},
+				{name: "RED", data: encodePicture(8, 8, 0, 0, func(x int, y int) int { return 1 })},
+			})
+			if _, err := wad.ReadTextures(); !errors.Is(err, test.want) {
+				t.Errorf("ReadTextures error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
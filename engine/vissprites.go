@@ -0,0 +1,243 @@
+package engine
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// minSpriteDistance is the distance in front of the viewer below which things are not drawn
+	minSpriteDistance = 4
+
+	// unclipped marks columns of the sprite clip arrays no drawseg has clipped yet
+	unclipped = -2
+)
+
+// vissprite is a thing projected onto the screen, drawn after all walls and planes.
+type vissprite struct {
+	x1         int
+	x2         int
+	gx         float64 // position in the map
+	gy         float64
+	gz         float64 // height of the bottom
+	gzt        float64 // height of the top
+	startFrac  float64 // picture column at x1
+	xStep      float64 // picture columns per screen column, negative for mirrored pictures
+	scale      float64 // screen rows per map unit, for comparing the depth with drawsegs
+	textureMid float64 // picture row at the height of the viewer's eyes
+	picture    *Picture
+	colormap   *Colormap
+}
+
+// addSprites projects the things of the sector of the given subsector. Each sector's things are only added once per
+// frame.
+func (r *Renderer) addSprites(sector int16) {
+	if r.spritesAdded[sector] == r.frame {
+		return
+	}
+	r.spritesAdded[sector] = r.frame
+	for _, thing := range r.sectorThings[sector] {
+		r.projectSprite(thing)
+	}
+}
+
+// sortThings assigns the things of the map to the sectors they are in.
+func (r *Renderer) sortThings() {
+	r.sectorThings = make([][]Thing, len(r.currentMap.Sectors))
+	r.spritesAdded = make([]int, len(r.currentMap.Sectors))
+	for _, thing := range r.currentMap.Things {
+		if thing.Flags&ThingMultiplayer != 0 || thing.Flags&ThingSkillMedium == 0 {
+			continue
+		}
+		if _, ok := thingSprites[thing.ThingType]; !ok {
+			continue
+		}
+		subSector := r.currentMap.SubSectors[r.currentMap.PointInSubSector(float64(thing.XPosition), float64(thing.YPosition))]
+		r.sectorThings[subSector.sector] = append(r.sectorThings[subSector.sector], thing)
+	}
+}
+
+// projectSprite adds the thing as a vissprite if it lies within the field of view.
+func (r *Renderer) projectSprite(thing Thing) {
+	info := thingSprites[thing.ThingType]
+	frame, ok := r.sprites.Frame(info.sprite, int(info.frame-'A'))
+	if !ok {
+		return
+	}
+
+	gx, gy := float64(thing.XPosition), float64(thing.YPosition)
+	trX, trY := gx-r.viewX, gy-r.viewY
+	viewCos, viewSin := math.Cos(r.viewAngle.radians()), math.Sin(r.viewAngle.radians())
+
+	// distance along the view direction and offset to the right of it
+	tz := trX*viewCos + trY*viewSin
+	if tz < minSpriteDistance {
+		return
+	}
+	xScale := r.projection / tz
+	yScale := r.yProjection / tz
+	tx := trX*viewSin - trY*viewCos
+	if math.Abs(tx) > tz*4 {
+		return // too far off the side
+	}
+
+	rotation := 0
+	if frame.Rotate {
+		angle := r.pointToAngle(gx, gy) - bamFromDegrees(float64(thing.Direction)) + bamAngle90/4*9
+		rotation = int(angle >> 29)
+	}
+	picture := frame.Pictures[rotation]
+	flip := frame.Flip[rotation]
+
+	tx -= float64(picture.LeftOffset)
+	x1 := int(math.Floor(r.centerX + tx*xScale))
+	if x1 > r.Width {
+		return
+	}
+	tx += float64(picture.Width)
+	x2 := int(math.Floor(r.centerX+tx*xScale)) - 1
+	if x2 < 0 {
+		return
+	}
+
+	sector := r.currentMap.Sectors[r.currentMap.SubSectors[r.currentMap.PointInSubSector(gx, gy)].sector]
+	vis := vissprite{
+		x1:      max(x1, 0),
+		x2:      min(x2, r.Width-1),
+		gx:      gx,
+		gy:      gy,
+		scale:   yScale,
+		picture: picture,
+	}
+	if info.hangs {
+		vis.gzt = float64(sector.ceilingHeight)
+		vis.gz = vis.gzt - float64(picture.TopOffset)
+	} else {
+		vis.gz = float64(sector.floorHeight)
+		vis.gzt = vis.gz + float64(picture.TopOffset)
+	}
+	vis.textureMid = vis.gzt - r.viewZ
+
+	vis.xStep = 1 / xScale
+	if flip {
+		vis.startFrac = float64(picture.Width) - 1
+		vis.xStep = -vis.xStep
+	}
+	if vis.x1 > x1 {
+		vis.startFrac += vis.xStep * float64(vis.x1-x1)
+	}
+
+	if info.bright {
+		vis.colormap = &r.colormaps[0]
+	} else {
+		vis.colormap = r.scaleColormap(lightIndex(sector.lightLevel, 0), yScale)
+	}
+	r.vissprites = append(r.vissprites, vis)
+}
+
+// drawMasked draws the vissprites of the frame from back to front, then the masked middle textures not drawn behind
+// any of them.
+func (r *Renderer) drawMasked() {
+	sort.SliceStable(r.vissprites, func(i, j int) bool {
+		return r.vissprites[i].scale < r.vissprites[j].scale
+	})
+	for i := range r.vissprites {
+		r.drawSprite(&r.vissprites[i])
+	}
+	for i := len(r.drawSegs) - 1; i >= 0; i-- {
+		if ds := &r.drawSegs[i]; ds.maskedTexture != nil {
+			r.renderMaskedSegRange(ds, ds.x1, ds.x2)
+		}
+	}
+}
+
+// drawSprite draws a vissprite clipped by the silhouettes of the segs in front of it.
+func (r *Renderer) drawSprite(vis *vissprite) {
+	for x := vis.x1; x <= vis.x2; x++ {
+		r.spriteTopClip[x] = unclipped
+		r.spriteBottomClip[x] = unclipped
+	}
+
+	// walk the drawsegs from the last drawn, farthest one to the nearest like the original engine; the first seg in
+	// front of the sprite sets the clip of a column
+	for i := len(r.drawSegs) - 1; i >= 0; i-- {
+		ds := &r.drawSegs[i]
+		if ds.x1 > vis.x2 || ds.x2 < vis.x1 || (ds.silhouette == 0 && ds.maskedTexture == nil) {
+			continue
+		}
+		r1 := max(ds.x1, vis.x1)
+		r2 := min(ds.x2, vis.x2)
+
+		lowScale, scale := min(ds.scale1, ds.scale2), max(ds.scale1, ds.scale2)
+		if scale < vis.scale || (lowScale < vis.scale && !r.pointOnSegSide(vis.gx, vis.gy, ds.seg)) {
+			// seg is behind the sprite, its masked texture has to be drawn first
+			if ds.maskedTexture != nil {
+				r.renderMaskedSegRange(ds, r1, r2)
+			}
+			continue
+		}
+
+		silhouette := ds.silhouette
+		if vis.gz >= ds.bottomSilHeight {
+			silhouette &^= silhouetteBottom
+		}
+		if vis.gzt <= ds.topSilHeight {
+			silhouette &^= silhouetteTop
+		}
+		for x := r1; x <= r2; x++ {
+			if silhouette&silhouetteBottom != 0 && r.spriteBottomClip[x] == unclipped {
+				r.spriteBottomClip[x] = ds.spriteBottomClip[x-ds.x1]
+			}
+			if silhouette&silhouetteTop != 0 && r.spriteTopClip[x] == unclipped {
+				r.spriteTopClip[x] = ds.spriteTopClip[x-ds.x1]
+			}
+		}
+	}
+
+	for x := vis.x1; x <= vis.x2; x++ {
+		if r.spriteBottomClip[x] == unclipped {
+			r.spriteBottomClip[x] = r.Height
+		}
+		if r.spriteTopClip[x] == unclipped {
+			r.spriteTopClip[x] = -1
+		}
+	}
+
+	frac := vis.startFrac
+	for x := vis.x1; x <= vis.x2; x++ {
+		column := min(max(int(frac), 0), vis.picture.Width-1)
+		r.drawMaskedColumn(x, vis.picture.Column(column), vis.textureMid, vis.scale, vis.colormap,
+			r.spriteTopClip[x], r.spriteBottomClip[x])
+		frac += vis.xStep
+	}
+}
+
+// pointOnSegSide reports whether the given position lies on the back side of the seg.
+func (r *Renderer) pointOnSegSide(x float64, y float64, seg *Seg) bool {
+	v1 := r.currentMap.Vertexes[seg.startingVertexNumber]
+	v2 := r.currentMap.Vertexes[seg.endingVertexNumber]
+	dx, dy := x-float64(v1.XPosition), y-float64(v1.YPosition)
+	lineDx, lineDy := float64(v2.XPosition-v1.XPosition), float64(v2.YPosition-v1.YPosition)
+	return dy*lineDx >= lineDy*dx
+}
+
+// drawMaskedColumn draws the posts of a picture or texture column in screen column x, leaving the transparent rows
+// untouched. Rows up to topClip and from bottomClip on are covered by nearer geometry.
+func (r *Renderer) drawMaskedColumn(x int, posts []Post, textureMid float64, scale float64, colormap *Colormap,
+	topClip int, bottomClip int) {
+	topScreen := r.centerY - textureMid*scale
+	step := 1 / scale
+	for _, post := range posts {
+		top := topScreen + scale*float64(post.TopDelta)
+		bottom := top + scale*float64(len(post.Pixels))
+		yl := max(int(math.Ceil(top)), topClip+1)
+		yh := min(int(math.Ceil(bottom))-1, bottomClip-1)
+
+		frac := textureMid - float64(post.TopDelta) + (float64(yl)-r.centerY)*step
+		for y := yl; y <= yh; y++ {
+			row := min(max(int(frac), 0), len(post.Pixels)-1)
+			r.Framebuffer.Pixels[y*r.Width+x] = colormap[post.Pixels[row]]
+			frac += step
+		}
+	}
+}
@@ -4,43 +4,115 @@ import (
 	"bytes"
 	_ "embed"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
-var wad []byte
-var wadHeader WadHeader
-var lumpData []byte
-var directories = make(map[string]Directory)
-var directory = make(map[int]Directory)
-var directoryIndex = make(map[string]int)
-
-// Index offsets between Map-marker and Map-objects
+// Names of the lumps following a map marker
 const (
-	ThingsOffset    int = 1
-	LineDefsOffset  int = 2
-	SideDefsOffset  int = 3
-	VertexesOffset  int = 4
-	SegsOffset      int = 5
-	SubSectorOffset int = 6
-	NodesOffset     int = 7
-	SectorsOffset   int = 8
-	RejectOffset    int = 9
-	BlockmapOffset  int = 10
+	ThingsLump    = "THINGS"
+	LinedefsLump  = "LINEDEFS"
+	SidedefsLump  = "SIDEDEFS"
+	VertexesLump  = "VERTEXES"
+	SegsLump      = "SEGS"
+	SubSectorLump = "SSECTORS"
+	NodesLump     = "NODES"
+	SectorsLump   = "SECTORS"
+	RejectLump    = "REJECT"
+	BlockmapLump  = "BLOCKMAP"
+	BehaviorLump  = "BEHAVIOR"
+	ScriptsLump   = "SCRIPTS"
+	TextmapLump   = "TEXTMAP"
 )
 
+// mapLumpNames lists every lump name that may be part of the block following a map marker, in any order.
+var mapLumpNames = map[string]bool{
+	ThingsLump:    true,
+	LinedefsLump:  true,
+	SidedefsLump:  true,
+	VertexesLump:  true,
+	SegsLump:      true,
+	SubSectorLump: true,
+	NodesLump:     true,
+	SectorsLump:   true,
+	RejectLump:    true,
+	BlockmapLump:  true,
+	BehaviorLump:  true,
+	ScriptsLump:   true,
+}
+
+// nonMapLumpNames lists lumps that never belong to a map block, so that a block ends at them. Namespace markers such
+// as S_START and F_END end a block as well.
+var nonMapLumpNames = map[string]bool{
+	"PLAYPAL":  true,
+	"COLORMAP": true,
+	"ENDOOM":   true,
+	"GENMIDI":  true,
+	"DMXGUS":   true,
+	"PNAMES":   true,
+	"TEXTURE1": true,
+	"TEXTURE2": true,
+	"DEHACKED": true,
+	"MAPINFO":  true,
+	"ENDMAP":   true,
+}
+
 // Block sizes (in bytes)
 const (
+	HeaderBlockSize    int32 = 12
 	ThingsBlockSize    int32 = 10
 	DirectoryBlockSize int32 = 16
 	VertexesBlockSize  int32 = 4
 	LinedefsBlockSize  int32 = 14
+	SidedefsBlockSize  int32 = 30
 	SegsBlockSize      int32 = 12
 	SubSectorBlockSize int32 = 4
 	NodeBlockSize      int32 = 28
-	SectorBlockSize    int32 = 24
+	SectorBlockSize    int32 = 26
+	RejectBlockSize    int32 = 1
+	BlockmapBlockSize  int32 = 2
 )
 
+// Errors returned while loading a WAD file. They are wrapped in a LumpError carrying the offending lump name and
+// offset, so callers can test for them with errors.Is.
+var (
+	ErrBadMagic             = errors.New("bad WAD identification, expected IWAD or PWAD")
+	ErrDirectoryOutOfBounds = errors.New("directory out of bounds")
+	ErrLumpOutOfBounds      = errors.New("lump out of bounds")
+	ErrShortLump            = errors.New("short lump")
+	ErrLumpSizeNotMultiple  = errors.New("lump size is not a multiple of the record size")
+	ErrLumpNotFound         = errors.New("lump not found")
+	ErrIndexOutOfRange      = errors.New("record refers to an index out of range")
+	ErrMissingMapLump       = errors.New("missing map lump")
+)
+
+// LumpError records an error and the lump (name and file offset) that caused it.
+type LumpError struct {
+	Lump   string
+	Offset int32
+	Err    error
+}
+
+func (e *LumpError) Error() string {
+	return fmt.Sprintf("lump %q at offset %d: %v", e.Lump, e.Offset, e.Err)
+}
+
+func (e *LumpError) Unwrap() error {
+	return e.Err
+}
+
+// Wad is a loaded WAD file. Several WADs can be opened side by side, each holding its own lump directory.
+type Wad struct {
+	header WadHeader
+	files  []io.ReaderAt // the loaded file followed by merged PWADs
+	lumps  []Directory   // in directory order, names may repeat (e.g. THINGS once per map)
+
+	pictures map[string]*Picture // decoded pictures by lump name
+}
+
 type WadHeader struct {
 	identification string
 	numLumps       int32
@@ -51,17 +123,26 @@ type Directory struct {
 	filepos int32
 	size    int32
 	name    string
+	file    int // index into Wad.files
 }
 
 type Map struct {
 	Name       string
 	Things     []Thing
 	Linedefs   []Linedef
+	Sidedefs   []Sidedef
 	Vertexes   []Vertex
 	Segs       []Seg
 	SubSectors []SubSector
 	Nodes      []Node
 	Sectors    []Sector
+	Reject     Reject
+	Blockmap   Blockmap
+}
+
+// CanSectorSee reports whether sector b may be visible from sector a according to the REJECT table.
+func (m *Map) CanSectorSee(a int, b int) bool {
+	return m.Reject.CanSectorSee(a, b)
 }
 
 // Thing (see: https://doomwiki.org/wiki/Thing)
@@ -73,11 +154,37 @@ type Thing struct {
 	Flags     int16
 }
 
+// Thing flags see: https://doomwiki.org/wiki/Thing#Flags
+const (
+	ThingSkillEasy   int16 = 0x0001 // skill levels 1 and 2
+	ThingSkillMedium int16 = 0x0002 // skill level 3
+	ThingSkillHard   int16 = 0x0004 // skill levels 4 and 5
+	ThingAmbush      int16 = 0x0008
+	ThingMultiplayer int16 = 0x0010 // only present in multiplayer games
+)
+
 type Vertex struct {
 	XPosition int16
 	YPosition int16
 }
 
+// Linedef flags see: https://doomwiki.org/wiki/Linedef#Linedef_flags
+const (
+	LinedefBlocking      int16 = 0x0001
+	LinedefBlockMonsters int16 = 0x0002
+	LinedefTwoSided      int16 = 0x0004
+	LinedefUpperUnpegged int16 = 0x0008
+	LinedefLowerUnpegged int16 = 0x0010
+	LinedefSecret        int16 = 0x0020
+	LinedefBlockSound    int16 = 0x0040
+	LinedefNeverOnMap    int16 = 0x0080
+	LinedefAlwaysOnMap   int16 = 0x0100
+)
+
+// NoSideDef marks a missing side of a one-sided Linedef.
+const NoSideDef int16 = -1
+
+// Linedef see: https://doomwiki.org/wiki/Linedef
 type Linedef struct {
 	StartVertex  int16
 	EndVertex    int16
@@ -88,58 +195,258 @@ type Linedef struct {
 	BackSideDef  int16
 }
 
-func LoadWadFile(path string) {
-	wad, err := os.ReadFile(path)
+// IsTwoSided reports whether the linedef has a sidedef on its back, i.e. separates two sectors.
+func (l Linedef) IsTwoSided() bool {
+	return l.BackSideDef != NoSideDef
+}
+
+// Sidedef see: https://doomwiki.org/wiki/Sidedef
+type Sidedef struct {
+	XOffset       int16
+	YOffset       int16
+	UpperTexture  string
+	LowerTexture  string
+	MiddleTexture string
+	Sector        int16
+}
+
+// Open opens the WAD file at the given path. Only the header and lump directory are read up front, lump data is read
+// from the file on demand, so the file stays open until Close is called.
+func Open(path string) (*Wad, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		panic(fmt.Sprintf("Could not read WAD file from path `%s`", path))
+		return nil, fmt.Errorf("could not read WAD file from path `%s`: %w", path, err)
 	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not read WAD file from path `%s`: %w", path, err)
+	}
+	wad, err := OpenReaderAt(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return wad, nil
+}
 
+// Load parses WAD data held in memory.
+func Load(data []byte) (*Wad, error) {
+	return OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+}
+
+// OpenReaderAt parses the header and lump directory of the WAD of the given size read through r. If r is an
+// io.Closer, it is closed by Close.
+func OpenReaderAt(r io.ReaderAt, size int64) (*Wad, error) {
 	// Header
-	wadHeader = WadHeader{
-		string(wad[0:4]),
-		readInt[int32](wad[4:8]),
-		readInt[int32](wad[8:12]),
+	headerData := make([]byte, HeaderBlockSize)
+	if n, _ := r.ReadAt(headerData, 0); n < len(headerData) {
+		return nil, &LumpError{Lump: "header", Offset: 0, Err: ErrShortLump}
+	}
+	header := WadHeader{
+		string(headerData[0:4]),
+		readInt[int32](headerData[4:8]),
+		readInt[int32](headerData[8:12]),
+	}
+	if header.identification != "IWAD" && header.identification != "PWAD" {
+		return nil, &LumpError{Lump: "header", Offset: 0, Err: ErrBadMagic}
+	}
+	directoryEnd := int64(header.offFat) + int64(header.numLumps)*int64(DirectoryBlockSize)
+	if header.offFat < 0 || header.numLumps < 0 || directoryEnd > size {
+		return nil, &LumpError{Lump: "directory", Offset: header.offFat, Err: ErrDirectoryOutOfBounds}
+	}
+	directoryData := make([]byte, directoryEnd-int64(header.offFat))
+	if n, _ := r.ReadAt(directoryData, int64(header.offFat)); n < len(directoryData) {
+		return nil, &LumpError{Lump: "directory", Offset: header.offFat, Err: ErrDirectoryOutOfBounds}
 	}
 
 	// Directories
-	index := wadHeader.offFat
-	i := 0
-	for index+DirectoryBlockSize < int32(len(wad)) {
-		name := string(bytes.Trim(wad[index+8:index+16], "\x00")) // trim null-terminated strings
-		directories[name] = Directory{filepos: readInt[int32](wad[index : index+4]), size: readInt[int32](wad[index+4 : index+8]), name: name}
-		directoryIndex[name] = i
-		directory[i] = directories[name]
-		i++
-		index += DirectoryBlockSize
-	}
+	wad := &Wad{
+		header: header,
+		files:  []io.ReaderAt{r},
+		lumps:  make([]Directory, 0, header.numLumps),
 
-	lumpData = wad[0:wadHeader.offFat]
+		pictures: make(map[string]*Picture),
+	}
+	for index := int32(0); index < int32(len(directoryData)); index += DirectoryBlockSize {
+		entry := Directory{
+			filepos: readInt[int32](directoryData[index : index+4]),
+			size:    readInt[int32](directoryData[index+4 : index+8]),
+			name:    readString(directoryData[index+8 : index+16]),
+		}
+		if entry.filepos < 0 || entry.size < 0 || int64(entry.filepos)+int64(entry.size) > size {
+			return nil, &LumpError{Lump: entry.name, Offset: entry.filepos, Err: ErrLumpOutOfBounds}
+		}
+		wad.lumps = append(wad.lumps, entry)
+	}
 
 	if isDebugModeEnabled() {
 		fmt.Println("--- HEADER ---")
-		fmt.Println(fmt.Sprintf("file type: %s", wadHeader.identification))
-		fmt.Println(fmt.Sprintf("num lumps: %d", wadHeader.numLumps))
-		fmt.Println(fmt.Sprintf("off FAT:   %d", wadHeader.offFat))
+		fmt.Println(fmt.Sprintf("file type: %s", wad.header.identification))
+		fmt.Println(fmt.Sprintf("num lumps: %d", wad.header.numLumps))
+		fmt.Println(fmt.Sprintf("off FAT:   %d", wad.header.offFat))
 
 		fmt.Println("--- DIRECTORIES ---")
-		for _, directory := range directories {
+		for _, directory := range wad.lumps {
 			fmt.Println(directory)
 		}
 	}
+	return wad, nil
+}
+
+// Close closes the files of the WAD and of all PWADs merged into it.
+func (w *Wad) Close() error {
+	var errs []error
+	for _, file := range w.files {
+		if closer, ok := file.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReadLumpIndexForName returns the directory index of the last lump with the given name. Like the original engine,
+// the search runs backwards so that later lumps take precedence over earlier ones of the same name.
+func (w *Wad) ReadLumpIndexForName(name string) (int, bool) {
+	return w.FindLumpInRange(name, -1, len(w.lumps))
+}
+
+// FindLumpInRange returns the directory index of the last lump with the given name that lies strictly between the
+// indexes first and last, e.g. between a map marker and the next one or between F_START and F_END.
+func (w *Wad) FindLumpInRange(name string, first int, last int) (int, bool) {
+	name = strings.ToUpper(name)
+	first = max(first, -1)
+	last = min(last, len(w.lumps))
+	for i := last - 1; i > first; i-- {
+		if w.lumps[i].name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// FindLumpInNamespace returns the directory index of the last lump with the given name between the given start and
+// end marker lumps (e.g. S_START and S_END).
+func (w *Wad) FindLumpInNamespace(name string, startMarker string, endMarker string) (int, bool) {
+	start, ok := w.ReadLumpIndexForName(startMarker)
+	if !ok {
+		return -1, false
+	}
+	end, ok := w.FindLumpInRange(endMarker, start, len(w.lumps))
+	if !ok {
+		return -1, false
+	}
+	return w.FindLumpInRange(name, start, end)
+}
+
+// ReadLumpIndexesForName returns the directory indexes of all lumps with the given name in directory order.
+func (w *Wad) ReadLumpIndexesForName(name string) []int {
+	name = strings.ToUpper(name)
+	var indexes []int
+	for i, lump := range w.lumps {
+		if lump.name == name {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// NumLumps returns the number of lumps in the directory.
+func (w *Wad) NumLumps() int {
+	return len(w.lumps)
+}
+
+func (w *Wad) ReadDirectoryForLumpIndex(index int) Directory {
+	return w.lumps[index]
+}
+
+// ListMaps returns the names of all maps in the WAD in directory order, e.g. E1M1..E4M9 or MAP01..MAP32, but also
+// maps with arbitrary names in PWADs. A map marker is any lump followed by THINGS, TEXTMAP or, for WADs written by
+// tools that reorder them, any other map lump. Maps replaced by a merged PWAD are listed once.
+func (w *Wad) ListMaps() []string {
+	var maps []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(w.lumps); i++ {
+		if !w.isMapMarker(i) {
+			continue
+		}
+		if name := w.lumps[i].name; !seen[name] {
+			seen[name] = true
+			maps = append(maps, name)
+		}
+		// lumps inside the block are not markers, even if followed by a map lump
+		_, end := w.readMapBlock(i)
+		i = end - 1
+	}
+	return maps
+}
+
+// isMapMarker reports whether the lump at the given index is followed by a map lump and so may start a map block.
+func (w *Wad) isMapMarker(index int) bool {
+	if index+1 >= len(w.lumps) || mapLumpNames[w.lumps[index].name] {
+		return false
+	}
+	next := w.lumps[index+1].name
+	return mapLumpNames[next] || next == TextmapLump
 }
 
-func readLumpIndexForName(name string) int {
-	return directoryIndex[name]
+// readMapBlock returns the lumps of the block following the map marker at the given index, keyed by name, and the
+// index of the first lump after the block. Unknown lumps inside the block, such as editor metadata, are skipped. The
+// block ends at a lump known not to belong to maps, at a map lump repeating or at the marker of the next map, which
+// is told apart from metadata by being followed by TEXTMAP or a map lump already in the block.
+func (w *Wad) readMapBlock(markerIndex int) (map[string]Directory, int) {
+	block := make(map[string]Directory)
+	i := markerIndex + 1
+	for ; i < len(w.lumps); i++ {
+		name := w.lumps[i].name
+		if mapLumpNames[name] {
+			if _, ok := block[name]; ok {
+				break // a map lump repeating belongs to the next map
+			}
+			block[name] = w.lumps[i]
+			continue
+		}
+		if nonMapLumpNames[name] || strings.HasSuffix(name, "_START") || strings.HasSuffix(name, "_END") {
+			break
+		}
+		if i+1 < len(w.lumps) {
+			next := w.lumps[i+1].name
+			if _, ok := block[next]; ok || next == TextmapLump {
+				break
+			}
+		}
+	}
+	return block, i
 }
 
-func ReadDirectoryForLumpIndex(index int) Directory {
-	return directory[index]
+// readMapLump returns the directory entry and data of the named lump in a map block. The lump size must be a whole
+// number of records of the given block size. A missing optional lump yields no data.
+func (w *Wad) readMapLump(mapName string, block map[string]Directory, name string, blockSize int32, required bool) (Directory, []byte, error) {
+	lumpDirectory, ok := block[name]
+	if !ok {
+		if !required {
+			return Directory{name: name}, nil, nil
+		}
+		return Directory{}, nil, &LumpError{Lump: name, Offset: 0, Err: fmt.Errorf("%w in %s", ErrMissingMapLump, mapName)}
+	}
+	if lumpDirectory.size%blockSize != 0 {
+		return Directory{}, nil, &LumpError{Lump: lumpDirectory.name, Offset: lumpDirectory.filepos, Err: ErrLumpSizeNotMultiple}
+	}
+	lumpData, err := w.ReadLumpData(lumpDirectory)
+	return lumpDirectory, lumpData, err
 }
 
-func ReadMapData(mapName string) Map {
-	lumpIndex := readLumpIndexForName(mapName)
-	thingsDirectory := ReadDirectoryForLumpIndex(lumpIndex + ThingsOffset)
-	thingsLumpData := ReadLumpData(thingsDirectory)
+// ReadMapData parses the lumps of the map with the given marker name (e.g. E1M1 or MAP01).
+func (w *Wad) ReadMapData(mapName string) (Map, error) {
+	lumpIndex, ok := w.ReadLumpIndexForName(mapName)
+	if !ok {
+		return Map{}, &LumpError{Lump: mapName, Offset: 0, Err: ErrLumpNotFound}
+	}
+	block, _ := w.readMapBlock(lumpIndex)
+	thingsDirectory, thingsLumpData, err := w.readMapLump(mapName, block, ThingsLump, ThingsBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 
 	// Thing
 	var things []Thing
@@ -154,8 +461,10 @@ func ReadMapData(mapName string) Map {
 	}
 
 	// Linedefs
-	linedefsDirectory := ReadDirectoryForLumpIndex(lumpIndex + LineDefsOffset)
-	linedefsLumpData := ReadLumpData(linedefsDirectory)
+	linedefsDirectory, linedefsLumpData, err := w.readMapLump(mapName, block, LinedefsLump, LinedefsBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 	var linedefs []Linedef
 	for entryOffset := int32(0); entryOffset < linedefsDirectory.size; entryOffset += LinedefsBlockSize {
 		linedefs = append(linedefs, Linedef{
@@ -169,9 +478,28 @@ func ReadMapData(mapName string) Map {
 		})
 	}
 
+	// Sidedefs
+	sidedefsDirectory, sidedefsLumpData, err := w.readMapLump(mapName, block, SidedefsLump, SidedefsBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
+	var sidedefs []Sidedef
+	for entryOffset := int32(0); entryOffset < sidedefsDirectory.size; entryOffset += SidedefsBlockSize {
+		sidedefs = append(sidedefs, Sidedef{
+			XOffset:       readInt[int16](sidedefsLumpData[0+entryOffset : 2+entryOffset]),
+			YOffset:       readInt[int16](sidedefsLumpData[2+entryOffset : 4+entryOffset]),
+			UpperTexture:  readString(sidedefsLumpData[4+entryOffset : 12+entryOffset]),
+			LowerTexture:  readString(sidedefsLumpData[12+entryOffset : 20+entryOffset]),
+			MiddleTexture: readString(sidedefsLumpData[20+entryOffset : 28+entryOffset]),
+			Sector:        readInt[int16](sidedefsLumpData[28+entryOffset : 30+entryOffset]),
+		})
+	}
+
 	// Vertexes
-	vertexesDirectory := ReadDirectoryForLumpIndex(lumpIndex + VertexesOffset)
-	vertexesLumpData := ReadLumpData(vertexesDirectory)
+	vertexesDirectory, vertexesLumpData, err := w.readMapLump(mapName, block, VertexesLump, VertexesBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 	var vertexes []Vertex
 	for entryOffset := int32(0); entryOffset < vertexesDirectory.size; entryOffset += VertexesBlockSize {
 		vertexes = append(vertexes, Vertex{
@@ -182,8 +510,10 @@ func ReadMapData(mapName string) Map {
 
 	// Segs
 	var segs []Seg
-	segsDirectory := ReadDirectoryForLumpIndex(lumpIndex + SegsOffset)
-	segsLumpData := ReadLumpData(segsDirectory)
+	segsDirectory, segsLumpData, err := w.readMapLump(mapName, block, SegsLump, SegsBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 	for entryOffset := int32(0); entryOffset < segsDirectory.size; entryOffset += SegsBlockSize {
 		segs = append(segs, Seg{
 			startingVertexNumber: readInt[int16](segsLumpData[0+entryOffset : 2+entryOffset]),
@@ -197,8 +527,10 @@ func ReadMapData(mapName string) Map {
 
 	// SubSectors
 	var subSectors []SubSector
-	subSectorDirectory := ReadDirectoryForLumpIndex(lumpIndex + SubSectorOffset)
-	subSectorLumpData := ReadLumpData(subSectorDirectory)
+	subSectorDirectory, subSectorLumpData, err := w.readMapLump(mapName, block, SubSectorLump, SubSectorBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 	for entryOffset := int32(0); entryOffset < subSectorDirectory.size; entryOffset += SubSectorBlockSize {
 		subSectors = append(subSectors, SubSector{
 			segCount:       readInt[int16](subSectorLumpData[0+entryOffset : 2+entryOffset]),
@@ -208,8 +540,10 @@ func ReadMapData(mapName string) Map {
 
 	// Nodes
 	var nodes []Node
-	nodeDirectory := ReadDirectoryForLumpIndex(lumpIndex + NodesOffset)
-	nodeLumpData := ReadLumpData(nodeDirectory)
+	nodeDirectory, nodeLumpData, err := w.readMapLump(mapName, block, NodesLump, NodeBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 	index := int16(0)
 	for entryOffset := int32(0); entryOffset < nodeDirectory.size; entryOffset += NodeBlockSize {
 		nodes = append(nodes, Node{
@@ -228,8 +562,10 @@ func ReadMapData(mapName string) Map {
 
 	// Sectors
 	var sectors []Sector
-	sectorDirectory := ReadDirectoryForLumpIndex(lumpIndex + SectorsOffset)
-	sectorLumpData := ReadLumpData(sectorDirectory)
+	sectorDirectory, sectorLumpData, err := w.readMapLump(mapName, block, SectorsLump, SectorBlockSize, true)
+	if err != nil {
+		return Map{}, err
+	}
 	for entryOffset := int32(0); entryOffset < sectorDirectory.size; entryOffset += SectorBlockSize {
 		sectors = append(sectors, Sector{
 			floorHeight:          readInt[int16](sectorLumpData[0+entryOffset : 2+entryOffset]),
@@ -242,51 +578,207 @@ func ReadMapData(mapName string) Map {
 		})
 	}
 
-	return Map{
+	// Reject
+	_, rejectLumpData, err := w.readMapLump(mapName, block, RejectLump, RejectBlockSize, false)
+	if err != nil {
+		return Map{}, err
+	}
+	reject := readReject(rejectLumpData, len(sectors))
+
+	// Blockmap
+	blockmapDirectory, blockmapLumpData, err := w.readMapLump(mapName, block, BlockmapLump, BlockmapBlockSize, false)
+	if err != nil {
+		return Map{}, err
+	}
+	var blockmap Blockmap
+	// an empty BLOCKMAP is left for the engine to build, like a missing one
+	if blockmapDirectory.size > 0 {
+		if blockmap, err = readBlockmap(blockmapDirectory, blockmapLumpData); err != nil {
+			return Map{}, err
+		}
+	}
+
+	currentMap := Map{
 		Name:       mapName,
 		Things:     things,
 		Vertexes:   vertexes,
 		Linedefs:   linedefs,
+		Sidedefs:   sidedefs,
 		Segs:       segs,
 		SubSectors: subSectors,
 		Nodes:      nodes,
+		Sectors:    sectors,
+		Reject:     reject,
+		Blockmap:   blockmap,
+	}
+	if err := currentMap.linkSectors(block); err != nil {
+		return Map{}, err
 	}
+	return currentMap, nil
 }
 
-func ReadLumpData(directory Directory) []byte {
-	return lumpData[directory.filepos : directory.filepos+directory.size]
+// linkSectors resolves the front and back sector of every seg through its linedef and sidedefs and assigns each
+// subsector the sector of its first seg. It also checks the vertex, seg and node indexes followed by the renderer, so
+// that a broken map fails to load instead of crashing later. Errors point at the record holding the index that is out
+// of range.
+func (m *Map) linkSectors(block map[string]Directory) error {
+	indexError := func(lump string, record int, blockSize int32, kind string, index int) error {
+		offset := int64(block[lump].filepos) + int64(record)*int64(blockSize)
+		return &LumpError{Lump: lump, Offset: int32(offset), Err: fmt.Errorf("%w: %s %d", ErrIndexOutOfRange, kind, index)}
+	}
+	sidedefSector := func(linedef int16, sidedef int16) (int16, error) {
+		if sidedef < 0 || int(sidedef) >= len(m.Sidedefs) {
+			return NoSector, indexError(LinedefsLump, int(linedef), LinedefsBlockSize, "sidedef", int(sidedef))
+		}
+		sector := m.Sidedefs[sidedef].Sector
+		if sector < 0 || int(sector) >= len(m.Sectors) {
+			return NoSector, indexError(SidedefsLump, int(sidedef), SidedefsBlockSize, "sector", int(sector))
+		}
+		return sector, nil
+	}
+
+	validVertex := func(vertex int16) bool {
+		return vertex >= 0 && int(vertex) < len(m.Vertexes)
+	}
+	for i, linedef := range m.Linedefs {
+		for _, vertex := range []int16{linedef.StartVertex, linedef.EndVertex} {
+			if !validVertex(vertex) {
+				return indexError(LinedefsLump, i, LinedefsBlockSize, "vertex", int(vertex))
+			}
+		}
+	}
+
+	for i := range m.Segs {
+		seg := &m.Segs[i]
+		for _, vertex := range []int16{seg.startingVertexNumber, seg.endingVertexNumber} {
+			if !validVertex(vertex) {
+				return indexError(SegsLump, i, SegsBlockSize, "vertex", int(vertex))
+			}
+		}
+		if seg.lineDefNumber < 0 || int(seg.lineDefNumber) >= len(m.Linedefs) {
+			return indexError(SegsLump, i, SegsBlockSize, "linedef", int(seg.lineDefNumber))
+		}
+		linedef := m.Linedefs[seg.lineDefNumber]
+		frontSideDef, backSideDef := linedef.FrontSideDef, linedef.BackSideDef
+		if seg.direction != 0 {
+			frontSideDef, backSideDef = backSideDef, frontSideDef
+		}
+
+		var err error
+		seg.sideDef = frontSideDef
+		if seg.frontSector, err = sidedefSector(seg.lineDefNumber, frontSideDef); err != nil {
+			return err
+		}
+		seg.backSector = NoSector
+		if linedef.Flags&LinedefTwoSided != 0 && backSideDef != NoSideDef {
+			if seg.backSector, err = sidedefSector(seg.lineDefNumber, backSideDef); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range m.SubSectors {
+		subSector := &m.SubSectors[i]
+		if subSector.firstSegNumber < 0 || int(subSector.firstSegNumber) >= len(m.Segs) {
+			return indexError(SubSectorLump, i, SubSectorBlockSize, "seg", int(subSector.firstSegNumber))
+		}
+		if lastSeg := int(subSector.firstSegNumber) + int(subSector.segCount) - 1; subSector.segCount < 0 ||
+			lastSeg >= len(m.Segs) {
+			return indexError(SubSectorLump, i, SubSectorBlockSize, "seg", lastSeg)
+		}
+		subSector.sector = m.Segs[subSector.firstSegNumber].frontSector
+	}
+
+	for i, node := range m.Nodes {
+		for side := 0; side < 2; side++ {
+			child := node.child(side)
+			if uint16(child)&subSectorFlag != 0 {
+				if subSectorIndex(child) >= len(m.SubSectors) {
+					return indexError(NodesLump, i, NodeBlockSize, "subsector", subSectorIndex(child))
+				}
+			} else if int(child) >= len(m.Nodes) {
+				return indexError(NodesLump, i, NodeBlockSize, "node", int(child))
+			}
+		}
+	}
+	return nil
 }
 
-func readInt[T int16 | int32 | int64](data []byte) T {
-	ret := T(0)
-	buf := bytes.NewBuffer(data)
-	err := binary.Read(buf, binary.LittleEndian, &ret)
-	if err != nil {
-		return T(0)
+// ReadLumpData reads the data of the given lump from its file.
+func (w *Wad) ReadLumpData(directory Directory) ([]byte, error) {
+	data := make([]byte, directory.size)
+	if directory.size == 0 {
+		return data, nil
+	}
+	// ReaderAt implementations may report io.EOF along with a complete read at the end of the file
+	if n, err := w.files[directory.file].ReadAt(data, int64(directory.filepos)); n < len(data) {
+		return nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: fmt.Errorf("%w: %w", ErrShortLump, err)}
 	}
+	return data, nil
+}
 
-	return ret
+// readLumpRecords returns the directory entry and data of the lump with the given name, checking that its size is a
+// whole number of records of the given block size.
+func (w *Wad) readLumpRecords(name string, blockSize int32) (Directory, []byte, error) {
+	index, ok := w.ReadLumpIndexForName(name)
+	if !ok {
+		return Directory{}, nil, &LumpError{Lump: name, Offset: 0, Err: ErrLumpNotFound}
+	}
+	directory := w.ReadDirectoryForLumpIndex(index)
+	if directory.size%blockSize != 0 {
+		return Directory{}, nil, &LumpError{Lump: directory.name, Offset: directory.filepos, Err: ErrLumpSizeNotMultiple}
+	}
+	data, err := w.ReadLumpData(directory)
+	return directory, data, err
 }
 
-func readString(data []byte) (ret string) {
-	buf := bytes.NewBuffer(data)
-	err := binary.Read(buf, binary.LittleEndian, &ret)
-	if err != nil {
-		return ""
+// ReadLumpDataForName returns the data of the lump with the given name.
+func (w *Wad) ReadLumpDataForName(name string) ([]byte, error) {
+	index, ok := w.ReadLumpIndexForName(name)
+	if !ok {
+		return nil, &LumpError{Lump: name, Offset: 0, Err: ErrLumpNotFound}
 	}
-	return
+	return w.ReadLumpData(w.ReadDirectoryForLumpIndex(index))
+}
+
+// readInt decodes a little-endian integer from the start of data. Lump sizes are validated before decoding, so data
+// always holds enough bytes for T.
+func readInt[T int16 | int32 | int64](data []byte) T {
+	var ret T
+	switch any(ret).(type) {
+	case int16:
+		return T(int16(binary.LittleEndian.Uint16(data)))
+	case int32:
+		return T(int32(binary.LittleEndian.Uint32(data)))
+	default:
+		return T(int64(binary.LittleEndian.Uint64(data)))
+	}
+}
+
+// readString decodes a fixed-size, null-padded string as used for lump and texture names.
+func readString(data []byte) string {
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+	return string(data)
 }
 
 func isDebugModeEnabled() bool {
 	return len(os.Args) > 1 && os.Args[1] == "debug"
 }
 
+// decodeBoundingBox splits a bounding box given as a 64-bit integer from the WAD file into its map coordinates.
+func decodeBoundingBox(data int64) (top int16, bottom int16, left int16, right int16) {
+	return int16(data & 0xffff), int16((data >> 16) & 0xffff), int16((data >> 32) & 0xffff), int16((data >> 48) & 0xffff)
+}
+
 // ConvertToBoundingBox converts data given as a 64-bit integer from the WAD file to the BoundingBox data structure.
 func ConvertToBoundingBox(data int64) BoundingBox {
-	boundingBoxRight := remapX(int16((data >> 48) & 0xffff))
-	boundingBoxLeft := remapX(int16((data >> 32) & 0xffff))
-	boundingBoxBottom := remapY(int16((data >> 16) & 0xffff))
-	boundingBoxTop := remapY(int16(data & 0xffff))
+	top, bottom, left, right := decodeBoundingBox(data)
+	boundingBoxRight := remapX(right)
+	boundingBoxLeft := remapX(left)
+	boundingBoxBottom := remapY(bottom)
+	boundingBoxTop := remapY(top)
 
 	return BoundingBox{
 		right:  boundingBoxRight,
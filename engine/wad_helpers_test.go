@@ -0,0 +1,304 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testLump is a lump of a WAD built by the tests.
+type testLump struct {
+	name string
+	data []byte
+}
+
+// buildWad returns the bytes of a WAD with the given identification (IWAD or PWAD) holding the given lumps in order.
+func buildWad(identification string, lumps []testLump) []byte {
+	var data bytes.Buffer
+	for _, lump := range lumps {
+		data.Write(lump.data)
+	}
+	var wad bytes.Buffer
+	wad.WriteString(identification)
+	writeLE(&wad, int32(len(lumps)), int32(HeaderBlockSize)+int32(data.Len()))
+	wad.Write(data.Bytes())
+	filepos := HeaderBlockSize
+	for _, lump := range lumps {
+		writeLE(&wad, filepos, int32(len(lump.data)))
+		wad.Write(name8(lump.name))
+		filepos += int32(len(lump.data))
+	}
+	return wad.Bytes()
+}
+
+// loadWad builds a WAD from the given lumps and parses it.
+func loadWad(t *testing.T, identification string, lumps []testLump) *Wad {
+	t.Helper()
+	wad, err := Load(buildWad(identification, lumps))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return wad
+}
+
+// lumpNames returns the names in the directory of the given WAD in order.
+func lumpNames(w *Wad) []string {
+	names := make([]string, w.NumLumps())
+	for i := range names {
+		names[i] = w.ReadDirectoryForLumpIndex(i).name
+	}
+	return names
+}
+
+func writeLE(buffer *bytes.Buffer, values ...any) {
+	for _, value := range values {
+		if err := binary.Write(buffer, binary.LittleEndian, value); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func le(values ...any) []byte {
+	var buffer bytes.Buffer
+	writeLE(&buffer, values...)
+	return buffer.Bytes()
+}
+
+func name8(name string) []byte {
+	data := make([]byte, 8)
+	copy(data, name)
+	return data
+}
+
+// encodePicture encodes a picture in the Doom picture format. pixel returns the palette index at a position, or -1
+// for transparent pixels.
+func encodePicture(width int, height int, leftOffset int, topOffset int, pixel func(x int, y int) int) []byte {
+	var columns bytes.Buffer
+	offsets := make([]int32, width)
+	for x := 0; x < width; x++ {
+		offsets[x] = int32(8 + 4*width + columns.Len())
+		for y := 0; y < height; {
+			if pixel(x, y) < 0 {
+				y++
+				continue
+			}
+			start := y
+			var pixels []byte
+			for y < height && pixel(x, y) >= 0 && len(pixels) < 128 {
+				pixels = append(pixels, byte(pixel(x, y)))
+				y++
+			}
+			columns.Write([]byte{byte(start), byte(len(pixels)), 0})
+			columns.Write(pixels)
+			columns.WriteByte(0)
+		}
+		columns.WriteByte(0xff)
+	}
+	var picture bytes.Buffer
+	writeLE(&picture, int16(width), int16(height), int16(leftOffset), int16(topOffset), offsets)
+	picture.Write(columns.Bytes())
+	return picture.Bytes()
+}
+
+// testMapLumps returns the lumps of a map with two rooms side by side: a room lit at 160 with the player start,
+// joined through a two-sided linedef with a masked grate to a brighter outdoor room with a raised nukage floor and a
+// sky ceiling. The lumps follow the marker in the usual order.
+func testMapLumps(name string) []testLump {
+	vertexes := [][2]int16{{0, 0}, {0, 256}, {256, 256}, {256, 0}, {512, 256}, {512, 0}}
+	// start, end, flags, special, tag, front, back
+	linedefs := [][7]int16{
+		{0, 1, LinedefBlocking, 0, 0, 0, NoSideDef},
+		{1, 2, LinedefBlocking, 0, 0, 1, NoSideDef},
+		{3, 0, LinedefBlocking, 0, 0, 2, NoSideDef},
+		{2, 3, LinedefTwoSided | LinedefLowerUnpegged, 0, 0, 3, 4},
+		{2, 4, LinedefBlocking, 0, 0, 5, NoSideDef},
+		{4, 5, LinedefBlocking | LinedefUpperUnpegged, 0, 0, 6, NoSideDef},
+		{5, 3, LinedefBlocking, 0, 0, 7, NoSideDef},
+	}
+	type sidedef struct {
+		xOffset, yOffset     int16
+		upper, lower, middle string
+		sector               int16
+	}
+	sidedefs := []sidedef{
+		{0, 0, "-", "-", "STARTAN", 0},
+		{0, 0, "-", "-", "STARTAN", 0},
+		{0, 0, "-", "-", "STARTAN", 0},
+		{0, 0, "STARTAN", "STARTAN", "GRATE", 0},
+		{0, 0, "STARTAN", "STARTAN", "-", 1},
+		{0, 0, "-", "-", "STARTAN", 1},
+		{8, 4, "-", "-", "STARTAN", 1},
+		{0, 0, "-", "-", "STARTAN", 1},
+	}
+	// angles in BAM of the segs along east, north, west and south
+	const east, north, west, south = int16(0), int16(0x4000), int16(-0x8000), int16(-0x4000)
+	// start, end, angle, linedef, direction, offset
+	segs := [][6]int16{
+		{0, 1, north, 0, 0, 0}, {1, 2, east, 1, 0, 0}, {2, 3, south, 3, 0, 0}, {3, 0, west, 2, 0, 0},
+		{3, 2, north, 3, 1, 0}, {2, 4, east, 4, 0, 0}, {4, 5, south, 5, 0, 0}, {5, 3, west, 6, 0, 0},
+	}
+
+	var data [9]bytes.Buffer
+	things, linedefData, sidedefData, vertexData, segData, subSectorData, nodeData, sectorData, blockmapData :=
+		&data[0], &data[1], &data[2], &data[3], &data[4], &data[5], &data[6], &data[7], &data[8]
+	const allSkills = int16(7) // easy, medium and hard
+
+	writeLE(things, int16(128), int16(128), int16(0), int16(1), allSkills)    // player 1 start
+	writeLE(things, int16(384), int16(128), int16(0), int16(2014), allSkills) // health bonus
+	writeLE(things, int16(200), int16(64), int16(90), int16(3001), allSkills) // imp
+	for _, linedef := range linedefs {
+		writeLE(linedefData, linedef)
+	}
+	for _, side := range sidedefs {
+		writeLE(sidedefData, side.xOffset, side.yOffset)
+		sidedefData.Write(name8(side.upper))
+		sidedefData.Write(name8(side.lower))
+		sidedefData.Write(name8(side.middle))
+		writeLE(sidedefData, side.sector)
+	}
+	for _, vertex := range vertexes {
+		writeLE(vertexData, vertex)
+	}
+	for _, seg := range segs {
+		writeLE(segData, seg)
+	}
+	writeLE(subSectorData, int16(4), int16(0), int16(4), int16(4))
+	// partition along x=256 pointing north, right box, left box, right child, left child
+	writeLE(nodeData, int16(256), int16(0), int16(0), int16(256),
+		[4]int16{256, 0, 256, 512}, [4]int16{256, 0, 0, 256}, uint16(0x8001), uint16(0x8000))
+	writeLE(sectorData, int16(0), int16(128))
+	sectorData.Write(name8("FLOOR0_1"))
+	sectorData.Write(name8("CEIL1_1"))
+	writeLE(sectorData, int16(160), int16(0), int16(0))
+	writeLE(sectorData, int16(16), int16(112))
+	sectorData.Write(name8("NUKAGE1"))
+	sectorData.Write(name8("F_SKY1"))
+	writeLE(sectorData, int16(200), int16(0), int16(0))
+
+	// one row of four 128 unit cells, each listing the linedefs crossing it
+	cells := [][]int16{{0, 1, 2}, {1, 2, 3}, {3, 4, 6}, {4, 5, 6}}
+	writeLE(blockmapData, int16(0), int16(0), int16(len(cells)), int16(1))
+	offset := 4 + len(cells)
+	for _, cell := range cells {
+		writeLE(blockmapData, uint16(offset))
+		offset += len(cell) + 2
+	}
+	for _, cell := range cells {
+		writeLE(blockmapData, int16(0), cell, int16(-1))
+	}
+
+	return []testLump{
+		{name: name},
+		{name: ThingsLump, data: things.Bytes()},
+		{name: LinedefsLump, data: linedefData.Bytes()},
+		{name: SidedefsLump, data: sidedefData.Bytes()},
+		{name: VertexesLump, data: vertexData.Bytes()},
+		{name: SegsLump, data: segData.Bytes()},
+		{name: SubSectorLump, data: subSectorData.Bytes()},
+		{name: NodesLump, data: nodeData.Bytes()},
+		{name: SectorsLump, data: sectorData.Bytes()},
+		{name: RejectLump, data: []byte{0}},
+		{name: BlockmapLump, data: blockmapData.Bytes()},
+	}
+}
+
+// testGraphicsLumps returns palettes, light tables, textures, sprites and flats for rendering the test map. Every
+// palette index has a distinct color, so that golden images keep the indexes.
+func testGraphicsLumps() []testLump {
+	var playpal bytes.Buffer
+	for p := 0; p < NumPalettes; p++ {
+		for i := 0; i < 256; i++ {
+			playpal.Write([]byte{byte(i), byte(i * 3), byte(255 - i)})
+		}
+	}
+	var colormap bytes.Buffer
+	for m := 0; m < NumColormaps; m++ {
+		for i := 0; i < 256; i++ {
+			colormap.WriteByte(byte(max(i-m*4, 0)))
+		}
+	}
+
+	texture := func(name string, width int16, height int16, patches ...[3]int16) []byte {
+		data := append(name8(name), le(int32(0), width, height, int32(0), int16(len(patches)))...)
+		for _, patch := range patches {
+			data = append(data, le(patch[0], patch[1], patch[2], int16(1), int16(0))...)
+		}
+		return data
+	}
+	definitions := [][]byte{
+		texture("AASHITTY", 64, 64, [3]int16{0, 0, 0}),
+		texture("STARTAN", 128, 128, [3]int16{0, 0, 0}, [3]int16{48, 0, 1}),
+		texture("GRATE", 64, 64, [3]int16{0, 0, 1}),
+		texture("SKY1", 256, 128, [3]int16{0, 0, 2}),
+	}
+	texture1 := le(int32(len(definitions)))
+	offset := 4 + 4*len(definitions)
+	for _, definition := range definitions {
+		texture1 = append(texture1, le(int32(offset))...)
+		offset += len(definition)
+	}
+	for _, definition := range definitions {
+		texture1 = append(texture1, definition...)
+	}
+
+	flat := func(base int) []byte {
+		pixels := make([]byte, FlatBlockSize)
+		for y := 0; y < FlatSize; y++ {
+			for x := 0; x < FlatSize; x++ {
+				pixels[y*FlatSize+x] = byte(base + (x^y)/8)
+			}
+		}
+		return pixels
+	}
+	imp := func(color int, x1 int, x2 int) []byte {
+		return encodePicture(32, 56, 16, 56, func(x int, y int) int {
+			if x > x1 && x < x2 {
+				return color
+			}
+			return -1
+		})
+	}
+
+	return []testLump{
+		{name: PlaypalLump, data: playpal.Bytes()},
+		{name: ColormapLump, data: colormap.Bytes()},
+		{name: PnamesLump, data: append(le(int32(3)), append(name8("WALL1"), append(name8("WALL2"), name8("SKYPAT")...)...)...)},
+		{name: Texture1Lump, data: texture1},
+		{name: SpriteStartMarker},
+		{name: "BON1A0", data: encodePicture(16, 16, 8, 16, func(x int, y int) int {
+			if (x-8)*(x-8)+(y-8)*(y-8) < 40 {
+				return 100 + x
+			}
+			return -1
+		})},
+		{name: "TROOA1", data: imp(60, 4, 28)},
+		{name: "TROOA2A8", data: imp(70, 4, 24)},
+		{name: "TROOA3A7", data: imp(80, 8, 24)},
+		{name: "TROOA4A6", data: imp(90, 8, 28)},
+		{name: "TROOA5", data: imp(95, 4, 28)},
+		{name: SpriteEndMarker},
+		{name: "P_START"},
+		{name: "WALL1", data: encodePicture(64, 128, 0, 0, func(x int, y int) int { return (x*2 + y) % 256 })},
+		{name: "WALL2", data: encodePicture(64, 64, 0, 0, func(x int, y int) int {
+			if (x/8+y/8)%2 != 0 {
+				return 200
+			}
+			return -1
+		})},
+		{name: "SKYPAT", data: encodePicture(256, 128, 0, 0, func(x int, y int) int { return 150 + y/8 })},
+		{name: "P_END"},
+		{name: FlatStartMarker},
+		{name: "FLOOR0_1", data: flat(40)},
+		{name: "CEIL1_1", data: flat(120)},
+		{name: SkyFlatName, data: flat(0)},
+		{name: "NUKAGE1", data: flat(30)},
+		{name: "NUKAGE2", data: flat(31)},
+		{name: "NUKAGE3", data: flat(32)},
+		{name: FlatEndMarker},
+	}
+}
+
+// testIwadLumps returns the lumps of an IWAD with the graphics and the test map as E1M1.
+func testIwadLumps() []testLump {
+	return append(testGraphicsLumps(), testMapLumps("E1M1")...)
+}
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoadErrors(t *testing.T) {
+	valid := buildWad("PWAD", []testLump{{name: "DEMO1", data: []byte("demo")}})
+	// patch returns a copy of the valid WAD with value written at offset
+	patch := func(offset int, value int32) []byte {
+		data := append([]byte(nil), valid...)
+		copy(data[offset:], le(value))
+		return data
+	}
+	directoryOffset := int(HeaderBlockSize) + len("demo")
+
+	tests := []struct {
+		name   string
+		data   []byte
+		err    error
+		lump   string
+		offset int32
+	}{
+		{"truncated header", valid[:HeaderBlockSize-1], ErrShortLump, "header", 0},
+		{"bad magic", append([]byte("JWAD"), valid[4:]...), ErrBadMagic, "header", 0},
+		{"directory past end", patch(8, int32(len(valid))), ErrDirectoryOutOfBounds, "directory", int32(len(valid))},
+		{"too many lumps", patch(4, 2), ErrDirectoryOutOfBounds, "directory", int32(directoryOffset)},
+		{"lump past end", patch(directoryOffset+4, 1000), ErrLumpOutOfBounds, "DEMO1", HeaderBlockSize},
+		{"negative lump offset", patch(directoryOffset, -1), ErrLumpOutOfBounds, "DEMO1", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(test.data)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Load error = %v, want %v", err, test.err)
+			}
+			var lumpError *LumpError
+			if !errors.As(err, &lumpError) {
+				t.Fatalf("Load error %v is not a *LumpError", err)
+			}
+			if lumpError.Lump != test.lump || lumpError.Offset != test.offset {
+				t.Errorf("error at %s offset %d, want %s offset %d", lumpError.Lump, lumpError.Offset, test.lump,
+					test.offset)
+			}
+		})
+	}
+}
+
+func TestReadMapData(t *testing.T) {
+	wad := loadWad(t, "IWAD", testMapLumps("E1M1"))
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if len(m.Things) != 3 || len(m.Linedefs) != 7 || len(m.Sidedefs) != 8 || len(m.Vertexes) != 6 ||
+		len(m.Segs) != 8 || len(m.SubSectors) != 2 || len(m.Nodes) != 1 || len(m.Sectors) != 2 {
+		t.Errorf("got %d things, %d linedefs, %d sidedefs, %d vertexes, %d segs, %d subsectors, %d nodes, %d sectors",
+			len(m.Things), len(m.Linedefs), len(m.Sidedefs), len(m.Vertexes), len(m.Segs), len(m.SubSectors),
+			len(m.Nodes), len(m.Sectors))
+	}
+	want := Sidedef{XOffset: 0, YOffset: 0, UpperTexture: "STARTAN", LowerTexture: "STARTAN", MiddleTexture: "GRATE"}
+	if m.Sidedefs[3] != want {
+		t.Errorf("sidedef 3 = %+v, want %+v", m.Sidedefs[3], want)
+	}
+	if m.Sidedefs[6].XOffset != 8 || m.Sidedefs[6].YOffset != 4 || m.Sidedefs[4].Sector != 1 {
+		t.Errorf("sidedef 6 offsets (%d, %d), sidedef 4 sector %d", m.Sidedefs[6].XOffset, m.Sidedefs[6].YOffset,
+			m.Sidedefs[4].Sector)
+	}
+	if m.Linedefs[0].BackSideDef != NoSideDef || m.Linedefs[3].BackSideDef != 4 {
+		t.Errorf("back sidedefs %d and %d, want %d and 4", m.Linedefs[0].BackSideDef, m.Linedefs[3].BackSideDef,
+			NoSideDef)
+	}
+	if got := m.Blockmap.Linedefs(2, 0); !slices.Equal(got, []int16{0, 3, 4, 6}) {
+		t.Errorf("blockmap cell (2, 0) = %v", got)
+	}
+	if !m.CanSectorSee(0, 1) {
+		t.Errorf("sector 1 not visible from sector 0")
+	}
+}
+
+func TestReadMapDataSectors(t *testing.T) {
+	wad := loadWad(t, "IWAD", testMapLumps("E1M1"))
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if m.Sectors[1].floorHeight != 16 || m.Sectors[1].nameOfCeilingTexture != "F_SKY1" || m.Sectors[1].lightLevel != 200 {
+		t.Errorf("sector 1 = %+v", m.Sectors[1])
+	}
+	if m.SubSectors[0].sector != 0 || m.SubSectors[1].sector != 1 {
+		t.Errorf("subsector sectors %d and %d, want 0 and 1", m.SubSectors[0].sector, m.SubSectors[1].sector)
+	}
+	if m.Segs[2].frontSector != 0 || m.Segs[2].backSector != 1 || m.Segs[4].frontSector != 1 {
+		t.Errorf("seg 2 sectors %d and %d, seg 4 front sector %d", m.Segs[2].frontSector, m.Segs[2].backSector,
+			m.Segs[4].frontSector)
+	}
+	if m.Segs[0].backSector != NoSector {
+		t.Errorf("one-sided seg 0 has back sector %d", m.Segs[0].backSector)
+	}
+}
+
+func TestReadMapDataIndexOutOfRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		lump      int   // index of the lump to patch in testMapLumps
+		record    int32 // record holding the index
+		field     int32 // offset of the index in the record
+		blockSize int32
+		value     uint16 // index written to the field
+	}{
+		{"front sidedef of linedef 1", 2, 1, 10, LinedefsBlockSize, 99},
+		{"back sidedef of linedef 3", 2, 3, 12, LinedefsBlockSize, 99},
+		{"sector of sidedef 4", 3, 4, 28, SidedefsBlockSize, 99},
+		{"linedef of seg 6", 5, 6, 6, SegsBlockSize, 99},
+		{"first seg of subsector 1", 6, 1, 2, SubSectorBlockSize, 99},
+		{"start vertex of linedef 2", 2, 2, 0, LinedefsBlockSize, 99},
+		{"end vertex of linedef 5", 2, 5, 2, LinedefsBlockSize, 99},
+		{"start vertex of seg 3", 5, 3, 0, SegsBlockSize, 99},
+		{"end vertex of seg 7", 5, 7, 2, SegsBlockSize, 99},
+		{"seg count of subsector 1", 6, 1, 0, SubSectorBlockSize, 99},
+		{"right child of node 0", 7, 0, 24, NodeBlockSize, 99},
+		{"left child of node 0", 7, 0, 26, NodeBlockSize, 1},
+		{"subsector child of node 0", 7, 0, 24, NodeBlockSize, subSectorFlag | 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lumps := testMapLumps("E1M1")
+			lump := &lumps[test.lump]
+			lump.data = slices.Clone(lump.data)
+			copy(lump.data[test.record*test.blockSize+test.field:], le(test.value))
+			wad := loadWad(t, "PWAD", lumps)
+			_, err := wad.ReadMapData("E1M1")
+			var lumpError *LumpError
+			if !errors.Is(err, ErrIndexOutOfRange) || !errors.As(err, &lumpError) {
+				t.Fatalf("ReadMapData error = %v, want %v", err, ErrIndexOutOfRange)
+			}
+			directory := wad.ReadDirectoryForLumpIndex(test.lump)
+			if want := directory.filepos + test.record*test.blockSize; lumpError.Lump != lump.name ||
+				lumpError.Offset != want {
+				t.Errorf("error at %s offset %d, want %s offset %d", lumpError.Lump, lumpError.Offset, lump.name,
+					want)
+			}
+		})
+	}
+}
+
+func TestReadMapDataReorderedLumps(t *testing.T) {
+	lumps := testMapLumps("E1M1")
+	slices.Reverse(lumps[1:])
+	wad := loadWad(t, "PWAD", lumps)
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if len(m.Linedefs) != 7 || len(m.Sectors) != 2 {
+		t.Errorf("got %d linedefs and %d sectors", len(m.Linedefs), len(m.Sectors))
+	}
+}
+
+func TestReadMapDataUnknownLump(t *testing.T) {
+	lumps := testMapLumps("E1M1")
+	lumps = slices.Insert(lumps, 3, testLump{name: "EDITMETA", data: []byte("editor data")})
+	wad := loadWad(t, "PWAD", lumps)
+	if _, err := wad.ReadMapData("E1M1"); err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if got := wad.ListMaps(); !slices.Equal(got, []string{"E1M1"}) {
+		t.Errorf("ListMaps = %v", got)
+	}
+}
+
+func TestReadMapDataMissingLump(t *testing.T) {
+	lumps := slices.DeleteFunc(testMapLumps("E1M1"), func(lump testLump) bool { return lump.name == SectorsLump })
+	wad := loadWad(t, "PWAD", lumps)
+	_, err := wad.ReadMapData("E1M1")
+	if !errors.Is(err, ErrMissingMapLump) {
+		t.Fatalf("ReadMapData error = %v, want %v", err, ErrMissingMapLump)
+	}
+	var lumpError *LumpError
+	if !errors.As(err, &lumpError) || lumpError.Lump != SectorsLump {
+		t.Errorf("error %v does not name %s", err, SectorsLump)
+	}
+}
+
+func TestReadMapDataOptionalLumps(t *testing.T) {
+	lumps := slices.DeleteFunc(testMapLumps("E1M1"), func(lump testLump) bool {
+		return lump.name == RejectLump || lump.name == BlockmapLump
+	})
+	wad := loadWad(t, "PWAD", lumps)
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if m.Blockmap.Columns != 0 || !m.CanSectorSee(0, 1) {
+		t.Errorf("got blockmap with %d columns, sector 1 visible %v", m.Blockmap.Columns, m.CanSectorSee(0, 1))
+	}
+}
+
+func TestReadMapDataEmptyBlockmap(t *testing.T) {
+	lumps := testMapLumps("E1M1")
+	for i := range lumps {
+		if lumps[i].name == BlockmapLump {
+			lumps[i].data = nil // left for the engine to build
+		}
+	}
+	wad := loadWad(t, "PWAD", lumps)
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if m.Blockmap.Columns != 0 {
+		t.Errorf("got blockmap with %d columns", m.Blockmap.Columns)
+	}
+}
+
+func TestReadMapDataNextMap(t *testing.T) {
+	// E1M1 lacks a blockmap, which must not be taken from E1M2
+	lumps := slices.DeleteFunc(testMapLumps("E1M1"), func(lump testLump) bool { return lump.name == BlockmapLump })
+	lumps = append(lumps, testMapLumps("E1M2")...)
+	wad := loadWad(t, "PWAD", lumps)
+	m, err := wad.ReadMapData("E1M1")
+	if err != nil {
+		t.Fatalf("ReadMapData: %v", err)
+	}
+	if m.Blockmap.Columns != 0 {
+		t.Errorf("E1M1 has a blockmap with %d columns", m.Blockmap.Columns)
+	}
+	if got := wad.ListMaps(); !slices.Equal(got, []string{"E1M1", "E1M2"}) {
+		t.Errorf("ListMaps = %v", got)
+	}
+}
+
+func TestListMaps(t *testing.T) {
+	lumps := []testLump{{name: "PLAYPAL", data: []byte("palette")}}
+	lumps = append(lumps, testMapLumps("E1M1")...)
+	reordered := testMapLumps("MYMAP")
+	slices.Reverse(reordered[1:])
+	lumps = append(lumps, reordered...)
+	lumps = append(lumps, testLump{name: "MAP01"}, testLump{name: TextmapLump, data: []byte("namespace = \"doom\";")},
+		testLump{name: "ENDMAP"})
+	wad := loadWad(t, "IWAD", lumps)
+	wad.Merge(loadWad(t, "PWAD", testMapLumps("E1M1")))
+	if got := wad.ListMaps(); !slices.Equal(got, []string{"E1M1", "MYMAP", "MAP01"}) {
+		t.Errorf("ListMaps = %v", got)
+	}
+}
+
+func TestReadMapDataLumpSizeNotMultiple(t *testing.T) {
+	// a THINGS lump of one and a half records
+	things := make([]byte, ThingsBlockSize+ThingsBlockSize/2)
+	wad := loadWad(t, "PWAD", []testLump{{name: "E1M1"}, {name: ThingsLump, data: things}})
+	_, err := wad.ReadMapData("E1M1")
+	if !errors.Is(err, ErrLumpSizeNotMultiple) {
+		t.Fatalf("ReadMapData error = %v, want %v", err, ErrLumpSizeNotMultiple)
+	}
+	var lumpError *LumpError
+	if !errors.As(err, &lumpError) || lumpError.Lump != ThingsLump || lumpError.Offset != HeaderBlockSize {
+		t.Errorf("error %v does not name THINGS at offset %d", err, HeaderBlockSize)
+	}
+}
+
+func TestReadMapDataNotFound(t *testing.T) {
+	wad := loadWad(t, "IWAD", []testLump{{name: "E1M1"}})
+	if _, err := wad.ReadMapData("E1M9"); !errors.Is(err, ErrLumpNotFound) {
+		t.Errorf("ReadMapData error = %v, want %v", err, ErrLumpNotFound)
+	}
+}
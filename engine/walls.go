@@ -0,0 +1,447 @@
+package engine
+
+import (
+	"math"
+)
+
+const (
+	// scale limits of walls very close to and far away from the viewer
+	minWallScale = 1.0 / 256
+	maxWallScale = 64
+
+	// untexturedWallColor is the palette index of walls whose texture is missing
+	untexturedWallColor = 88
+)
+
+// wallRange holds the state of the seg columns being drawn, like the rw_ variables of the original engine.
+type wallRange struct {
+	normalAngle bam
+	distance    float64 // perpendicular distance from the viewer to the seg's line
+	scale       float64
+	scaleStep   float64
+
+	// screen rows of the front sector's ceiling and floor
+	topFrac    float64
+	topStep    float64
+	bottomFrac float64
+	bottomStep float64
+
+	// screen rows of the back sector's ceiling and floor, bounding the upper and lower sections
+	pixHigh     float64
+	pixHighStep float64
+	pixLow      float64
+	pixLowStep  float64
+
+	drawMiddle    bool  // one-sided seg covering the whole opening
+	maskedColumns []int // texture columns of the masked middle texture of a two-sided seg, nil if there is none
+	drawTop       bool
+	drawBottom    bool
+	midTexture    *wallTexture
+	topTexture    *wallTexture
+	bottomTexture *wallTexture
+	offset        float64 // texture column at the point of the seg's line closest to the viewer
+	centerAngle   bam     // view direction relative to the line from the viewer to that point
+	light         int     // light index of the seg
+	markCeiling   bool    // whether the front sector's ceiling shows above the seg
+	markFloor     bool    // whether the front sector's floor shows below the seg
+}
+
+// wallTexture is a texture to draw on a wall section. The texture is nil if it is missing from the WAD.
+type wallTexture struct {
+	texture    *Texture
+	textureMid float64 // texture row at the height of the viewer's eyes
+	missing    bool    // a texture is named but not found
+}
+
+// storeWallRange draws the columns [start, stop] of the current seg.
+func (r *Renderer) storeWallRange(start int, stop int) {
+	seg := r.currentSeg
+	v1 := r.currentMap.Vertexes[seg.startingVertexNumber]
+
+	var wall wallRange
+	wall.normalAngle = bamFromSegAngle(seg.angle) + bamAngle90
+	offsetAngle := min((wall.normalAngle - r.rwAngle1).abs(), bamAngle90)
+	distAngle := bamAngle90 - offsetAngle
+	hypotenuse := r.pointToDist(float64(v1.XPosition), float64(v1.YPosition))
+	wall.distance = hypotenuse * math.Sin(distAngle.radians())
+
+	wall.scale = r.scaleFromGlobalAngle(r.viewAngle+r.xToViewAngle[start], wall)
+	ds := drawSeg{seg: seg, x1: start, x2: stop, scale1: wall.scale, scale2: wall.scale}
+	if stop > start {
+		ds.scale2 = r.scaleFromGlobalAngle(r.viewAngle+r.xToViewAngle[stop], wall)
+		wall.scaleStep = (ds.scale2 - wall.scale) / float64(stop-start)
+	}
+
+	linedef := r.currentMap.Linedefs[seg.lineDefNumber]
+	sidedef := r.currentMap.Sidedefs[seg.sideDef]
+	front, back := r.frontSector, r.backSector
+	worldTop := float64(front.ceilingHeight) - r.viewZ
+	worldBottom := float64(front.floorHeight) - r.viewZ
+
+	if back == nil {
+		wall.drawMiddle = true
+		wall.midTexture = r.wallTexture(sidedef.MiddleTexture)
+		if linedef.Flags&LinedefLowerUnpegged != 0 {
+			// bottom of the texture at the floor
+			wall.midTexture.textureMid = worldBottom + float64(wall.midTexture.height())
+		} else {
+			// top of the texture at the ceiling
+			wall.midTexture.textureMid = worldTop
+		}
+		wall.midTexture.textureMid += float64(sidedef.YOffset)
+		wall.markCeiling = true
+		wall.markFloor = true
+
+		ds.silhouette = silhouetteBoth
+		ds.spriteTopClip = r.screenHeightArray[start : stop+1]
+		ds.spriteBottomClip = r.negOneArray[start : stop+1]
+		ds.bottomSilHeight = math.Inf(1)
+		ds.topSilHeight = math.Inf(-1)
+	} else {
+		ds.silhouette, ds.bottomSilHeight, ds.topSilHeight = r.silhouette()
+		if texture, ok := r.textures.Lookup(sidedef.MiddleTexture); ok {
+			// drawn after all solid geometry, interleaved with sprites
+			ds.maskedTexture = texture
+			ds.maskedColumns = make([]int, stop-start+1)
+			wall.maskedColumns = ds.maskedColumns
+		}
+		if back.ceilingHeight <= front.floorHeight {
+			ds.spriteBottomClip = r.negOneArray[start : stop+1]
+		}
+		if back.floorHeight >= front.ceilingHeight {
+			ds.spriteTopClip = r.screenHeightArray[start : stop+1]
+		}
+
+		worldHigh := float64(back.ceilingHeight) - r.viewZ
+		worldLow := float64(back.floorHeight) - r.viewZ
+
+		// no upper wall between two sky sectors, so outdoor areas can change height
+		if front.nameOfCeilingTexture == SkyFlatName && back.nameOfCeilingTexture == SkyFlatName {
+			worldTop = worldHigh
+		}
+
+		wall.markFloor = worldLow != worldBottom || back.nameOfFloorTexture != front.nameOfFloorTexture ||
+			back.lightLevel != front.lightLevel
+		wall.markCeiling = worldHigh != worldTop || back.nameOfCeilingTexture != front.nameOfCeilingTexture ||
+			back.lightLevel != front.lightLevel
+		if back.ceilingHeight <= front.floorHeight || back.floorHeight >= front.ceilingHeight {
+			// closed door
+			wall.markCeiling = true
+			wall.markFloor = true
+		}
+
+		// like in the original engine, a "-" upper or lower texture leaves the section undrawn and open
+		if worldHigh < worldTop && sidedef.UpperTexture != NoTexture {
+			wall.drawTop = true
+			wall.topTexture = r.wallTexture(sidedef.UpperTexture)
+			if linedef.Flags&LinedefUpperUnpegged != 0 {
+				// top of the texture at the front ceiling
+				wall.topTexture.textureMid = worldTop
+			} else {
+				// bottom of the texture at the back ceiling, so it moves with doors
+				wall.topTexture.textureMid = worldHigh + float64(wall.topTexture.height())
+			}
+			wall.topTexture.textureMid += float64(sidedef.YOffset)
+			wall.pixHigh = r.centerY - worldHigh*wall.scale
+			wall.pixHighStep = -wall.scaleStep * worldHigh
+		}
+		if worldLow > worldBottom && sidedef.LowerTexture != NoTexture {
+			wall.drawBottom = true
+			wall.bottomTexture = r.wallTexture(sidedef.LowerTexture)
+			if linedef.Flags&LinedefLowerUnpegged != 0 {
+				// aligned as if the texture started at the front ceiling
+				wall.bottomTexture.textureMid = worldTop
+			} else {
+				// top of the texture at the back floor, so it moves with lifts
+				wall.bottomTexture.textureMid = worldLow
+			}
+			wall.bottomTexture.textureMid += float64(sidedef.YOffset)
+			wall.pixLow = r.centerY - worldLow*wall.scale
+			wall.pixLowStep = -wall.scaleStep * worldLow
+		}
+	}
+
+	// horizontal texture position: distance along the line from the point closest to the viewer
+	offsetAngle = wall.normalAngle - r.rwAngle1
+	wall.offset = hypotenuse * math.Sin(min(offsetAngle.abs(), bamAngle90).radians())
+	if offsetAngle < bamAngle180 {
+		wall.offset = -wall.offset
+	}
+	wall.offset += float64(sidedef.XOffset) + float64(seg.offset)
+	wall.centerAngle = r.viewAngle - wall.normalAngle
+	wall.light = r.segLightIndex()
+
+	// planes on the far side of the viewer are not visible
+	if float64(front.floorHeight) >= r.viewZ || r.floorPlane == nil {
+		wall.markFloor = false
+	}
+	if (float64(front.ceilingHeight) <= r.viewZ && front.nameOfCeilingTexture != SkyFlatName) || r.ceilingPlane == nil {
+		wall.markCeiling = false
+	}
+	if wall.markCeiling {
+		r.ceilingPlane = r.checkPlane(r.ceilingPlane, start, stop)
+	}
+	if wall.markFloor {
+		r.floorPlane = r.checkPlane(r.floorPlane, start, stop)
+	}
+
+	wall.topFrac = r.centerY - worldTop*wall.scale
+	wall.topStep = -wall.scaleStep * worldTop
+	wall.bottomFrac = r.centerY - worldBottom*wall.scale
+	wall.bottomStep = -wall.scaleStep * worldBottom
+
+	r.renderSegLoop(start, stop, &wall)
+
+	// sprites and masked textures behind the opening of a two-sided seg are clipped to it
+	masked := ds.maskedTexture != nil
+	if (ds.silhouette&silhouetteTop != 0 || masked) && ds.spriteTopClip == nil {
+		ds.spriteTopClip = r.saveOpening(r.ceilingClip[start : stop+1])
+	}
+	if (ds.silhouette&silhouetteBottom != 0 || masked) && ds.spriteBottomClip == nil {
+		ds.spriteBottomClip = r.saveOpening(r.floorClip[start : stop+1])
+	}
+	if masked && ds.silhouette&silhouetteTop == 0 {
+		ds.silhouette |= silhouetteTop
+		ds.topSilHeight = math.Inf(-1)
+	}
+	if masked && ds.silhouette&silhouetteBottom == 0 {
+		ds.silhouette |= silhouetteBottom
+		ds.bottomSilHeight = math.Inf(1)
+	}
+	r.drawSegs = append(r.drawSegs, ds)
+}
+
+// Silhouettes of drawsegs: the parts of the screen a seg hides sprites behind it in.
+const (
+	silhouetteBottom = 1
+	silhouetteTop    = 2
+	silhouetteBoth   = 3
+)
+
+// drawSeg records the screen columns a seg has been drawn in, so sprites can be clipped against it.
+type drawSeg struct {
+	seg              *Seg
+	x1               int
+	x2               int
+	scale1           float64
+	scale2           float64
+	silhouette       int
+	bottomSilHeight  float64 // sprites reaching below this height are clipped by the bottom silhouette
+	topSilHeight     float64 // sprites reaching above this height are clipped by the top silhouette
+	spriteTopClip    []int   // per column from x1, the lowest row covered from above
+	spriteBottomClip []int   // per column from x1, the highest row covered from below
+	maskedTexture    *Texture
+	maskedColumns    []int // per column from x1, the texture column to draw or maskedColumnDrawn
+}
+
+// maskedColumnDrawn marks the columns of a masked middle texture that have already been drawn.
+const maskedColumnDrawn = math.MaxInt32
+
+// silhouette returns which parts of the screen the current two-sided seg hides sprites behind it in, along with the
+// heights the bottom and top silhouettes apply below and above.
+func (r *Renderer) silhouette() (int, float64, float64) {
+	front, back := r.frontSector, r.backSector
+	silhouette := 0
+	bottomSilHeight := math.Inf(1)
+	topSilHeight := math.Inf(-1)
+	if front.floorHeight > back.floorHeight {
+		silhouette |= silhouetteBottom
+		bottomSilHeight = float64(front.floorHeight)
+	} else if float64(back.floorHeight) > r.viewZ {
+		silhouette |= silhouetteBottom
+	}
+	if front.ceilingHeight < back.ceilingHeight {
+		silhouette |= silhouetteTop
+		topSilHeight = float64(front.ceilingHeight)
+	} else if float64(back.ceilingHeight) < r.viewZ {
+		silhouette |= silhouetteTop
+	}
+
+	// closed doors hide everything behind them
+	if back.ceilingHeight <= front.floorHeight {
+		silhouette |= silhouetteBottom
+		bottomSilHeight = math.Inf(1)
+	}
+	if back.floorHeight >= front.ceilingHeight {
+		silhouette |= silhouetteTop
+		topSilHeight = math.Inf(-1)
+	}
+	return silhouette, bottomSilHeight, topSilHeight
+}
+
+// saveOpening keeps a copy of a range of a clip array for the rest of the frame.
+func (r *Renderer) saveOpening(clip []int) []int {
+	start := len(r.openings)
+	r.openings = append(r.openings, clip...)
+	return r.openings[start:len(r.openings):len(r.openings)]
+}
+
+// scaleFromGlobalAngle returns the scale of the wall at the given view angle, i.e. the size of a map unit on screen.
+func (r *Renderer) scaleFromGlobalAngle(visAngle bam, wall wallRange) float64 {
+	angleA := bamAngle90 + (visAngle - r.viewAngle)
+	angleB := bamAngle90 + (visAngle - wall.normalAngle)
+	numerator := r.yProjection * math.Sin(angleB.radians())
+	denominator := wall.distance * math.Sin(angleA.radians())
+	// the limits were chosen for the original resolution
+	minScale := minWallScale * r.yProjection / originalProjection
+	maxScale := maxWallScale * r.yProjection / originalProjection
+	if denominator <= numerator/maxWallScale/(1<<10) {
+		return maxScale
+	}
+	return min(max(numerator/denominator, minScale), maxScale)
+}
+
+// renderSegLoop draws the wall columns [start, stop] and updates the clip arrays: one-sided walls cover their columns
+// completely, upper and lower sections of two-sided walls narrow the opening the rest of the view is seen through.
+func (r *Renderer) renderSegLoop(start int, stop int, wall *wallRange) {
+	for x := start; x <= stop; x++ {
+		yl := max(int(math.Ceil(wall.topFrac)), r.ceilingClip[x]+1)
+		if wall.markCeiling {
+			top := r.ceilingClip[x] + 1
+			bottom := min(yl-1, r.floorClip[x]-1)
+			if top <= bottom {
+				r.ceilingPlane.top[x+1] = top
+				r.ceilingPlane.bottom[x+1] = bottom
+			}
+		}
+
+		yh := min(int(math.Floor(wall.bottomFrac)), r.floorClip[x]-1)
+		if wall.markFloor {
+			top := max(yh+1, r.ceilingClip[x]+1)
+			bottom := r.floorClip[x] - 1
+			if top <= bottom {
+				r.floorPlane.top[x+1] = top
+				r.floorPlane.bottom[x+1] = bottom
+			}
+		}
+
+		// texture column and scale of this screen column
+		angle := wall.centerAngle + r.xToViewAngle[x]
+		textureColumn := int(math.Floor(wall.offset - math.Tan(angle.signedRadians())*wall.distance))
+		column := wallColumn{x: x, textureColumn: textureColumn, step: 1 / wall.scale, colormap: r.scaleColormap(wall.light, wall.scale)}
+
+		if wall.maskedColumns != nil {
+			wall.maskedColumns[x-start] = textureColumn
+		}
+
+		if wall.drawMiddle {
+			r.drawWallColumn(column, yl, yh, wall.midTexture)
+			r.ceilingClip[x] = r.Height
+			r.floorClip[x] = -1
+		} else {
+			if wall.drawTop {
+				mid := min(int(math.Floor(wall.pixHigh)), r.floorClip[x]-1)
+				wall.pixHigh += wall.pixHighStep
+				if mid >= yl {
+					r.drawWallColumn(column, yl, mid, wall.topTexture)
+					r.ceilingClip[x] = mid
+				} else {
+					r.ceilingClip[x] = yl - 1
+				}
+			} else if wall.markCeiling {
+				r.ceilingClip[x] = yl - 1
+			}
+
+			if wall.drawBottom {
+				mid := max(int(math.Ceil(wall.pixLow)), r.ceilingClip[x]+1)
+				wall.pixLow += wall.pixLowStep
+				if mid <= yh {
+					r.drawWallColumn(column, mid, yh, wall.bottomTexture)
+					r.floorClip[x] = mid
+				} else {
+					r.floorClip[x] = yh + 1
+				}
+			} else if wall.markFloor {
+				r.floorClip[x] = yh + 1
+			}
+		}
+
+		wall.scale += wall.scaleStep
+		wall.topFrac += wall.topStep
+		wall.bottomFrac += wall.bottomStep
+	}
+}
+
+// wallTexture looks up the named texture for a wall section. Sections without a texture ("-") are not drawn, those
+// whose texture cannot be found are drawn untextured.
+func (r *Renderer) wallTexture(name string) *wallTexture {
+	texture, _ := r.textures.Lookup(name)
+	return &wallTexture{texture: texture, missing: name != NoTexture && texture == nil}
+}
+
+func (t *wallTexture) height() int {
+	if t.texture == nil {
+		return 0
+	}
+	return t.texture.Height
+}
+
+// wallColumn describes a screen column of a wall.
+type wallColumn struct {
+	x             int
+	textureColumn int
+	step          float64 // texture rows per screen row
+	colormap      *Colormap
+}
+
+// drawWallColumn draws the rows [yl, yh] of a wall column with the given texture. Textures repeat vertically.
+func (r *Renderer) drawWallColumn(column wallColumn, yl int, yh int, texture *wallTexture) {
+	if texture.texture == nil {
+		if !texture.missing {
+			return
+		}
+		for y := yl; y <= yh; y++ {
+			r.Framebuffer.Pixels[y*r.Width+column.x] = column.colormap[untexturedWallColor]
+		}
+		return
+	}
+
+	source := texture.texture.Column(column.textureColumn)
+	height := len(source)
+	frac := texture.textureMid + (float64(yl)-r.centerY)*column.step
+	for y := yl; y <= yh; y++ {
+		row := int(math.Floor(frac)) % height
+		if row < 0 {
+			row += height
+		}
+		r.Framebuffer.Pixels[y*r.Width+column.x] = column.colormap[source[row]]
+		frac += column.step
+	}
+}
+
+// renderMaskedSegRange draws the columns [x1, x2] of the masked middle texture of a drawseg. Like the original engine,
+// the texture is not repeated vertically.
+func (r *Renderer) renderMaskedSegRange(ds *drawSeg, x1 int, x2 int) {
+	seg := ds.seg
+	linedef := r.currentMap.Linedefs[seg.lineDefNumber]
+	sidedef := r.currentMap.Sidedefs[seg.sideDef]
+	front := &r.currentMap.Sectors[seg.frontSector]
+	back := &r.currentMap.Sectors[seg.backSector]
+
+	r.currentSeg = seg
+	r.frontSector = front
+	light := r.segLightIndex()
+
+	var textureMid float64
+	if linedef.Flags&LinedefLowerUnpegged != 0 {
+		textureMid = float64(max(front.floorHeight, back.floorHeight)) + float64(ds.maskedTexture.Height) - r.viewZ
+	} else {
+		textureMid = float64(min(front.ceilingHeight, back.ceilingHeight)) - r.viewZ
+	}
+	textureMid += float64(sidedef.YOffset)
+
+	scaleStep := 0.0
+	if ds.x2 > ds.x1 {
+		scaleStep = (ds.scale2 - ds.scale1) / float64(ds.x2-ds.x1)
+	}
+	scale := ds.scale1 + float64(x1-ds.x1)*scaleStep
+	for x := x1; x <= x2; x++ {
+		i := x - ds.x1
+		if ds.maskedColumns[i] != maskedColumnDrawn {
+			r.drawMaskedColumn(x, ds.maskedTexture.MaskedColumn(ds.maskedColumns[i]), textureMid, scale,
+				r.scaleColormap(light, scale), ds.spriteTopClip[i], ds.spriteBottomClip[i])
+			ds.maskedColumns[i] = maskedColumnDrawn
+		}
+		scale += scaleStep
+	}
+}
@@ -4,45 +4,193 @@ import (
 	"fmt"
 	"github.com/christopher-weiss/GoDoom/engine"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"log"
 	"math"
 	"os"
+	"strings"
 )
 
 type Game struct{}
 
-var mapData = make(map[string]engine.Map)
-var currentMap engine.Map
+var wad *engine.Wad
+var maps *engine.MapCache
+var mapNames []string
+var currentMapIndex int
+var currentMap *engine.Map
+var renderer *engine.Renderer
+var automap *engine.Framebuffer
+var showMap bool
+var tic int
+var screenshotRequested bool
+
+// mapSelectionKeys select the first nine maps of the WAD, PageUp/PageDown cycle through all of them
+var mapSelectionKeys = []ebiten.Key{
+	ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9,
+}
 
 func main() {
 	if len(os.Args) <= 1 {
-		fmt.Println("Usage: ./GoDoom <path to WAD file>")
+		fmt.Println("Usage: ./GoDoom <path to WAD file> [-file <path to PWAD file>...] [-resolution WIDTHxHEIGHT] [-noaspect] [-screenshot [-automap]]")
 		os.Exit(1)
 	}
 	var wadPath = os.Args[1]
-	initializeGame(wadPath)
+	var pwadPaths = readFileArguments(os.Args[2:])
+	resolution, err := readResolutionArguments(os.Args[2:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := initializeGame(wadPath, pwadPaths, resolution); err != nil {
+		log.Fatal(err)
+	}
+	if hasArgument(os.Args[2:], "-screenshot") {
+		if err := saveScreenshots(hasArgument(os.Args[2:], "-automap")); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	if err := ebiten.RunGame(&Game{}); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func initializeGame(wadPath string) {
-	ebiten.SetWindowSize(engine.ScreenResX, engine.ScreenRexY)
+// readFileArguments returns the PWAD paths following the -file parameter, up to the next parameter starting with '-'.
+func readFileArguments(args []string) []string {
+	var paths []string
+	inFileList := false
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			inFileList = arg == "-file"
+			continue
+		}
+		if inFileList {
+			paths = append(paths, arg)
+		}
+	}
+	return paths
+}
+
+// readResolutionArguments returns the resolution given with the -resolution parameter, with square pixels if
+// -noaspect is present.
+func readResolutionArguments(args []string) (engine.Resolution, error) {
+	resolution := engine.DefaultResolution
+	for i, arg := range args {
+		if arg == "-resolution" && i+1 < len(args) {
+			var err error
+			if resolution, err = engine.ParseResolution(args[i+1]); err != nil {
+				return engine.Resolution{}, err
+			}
+		}
+	}
+	if hasArgument(args, "-noaspect") {
+		resolution.PixelAspect = 1
+	}
+	return resolution, nil
+}
+
+// hasArgument reports whether the given parameter is present.
+func hasArgument(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// saveScreenshots renders the first map from the player start without opening a window and saves the frame, and
+// optionally the automap, as screenshots.
+func saveScreenshots(withAutomap bool) error {
+	renderer.RenderPlayerView(currentMap)
+	frames := []*engine.Framebuffer{renderer.Framebuffer}
+	if withAutomap {
+		engine.DrawMap(automap, currentMap)
+		frames = append(frames, automap)
+	}
+	for _, frame := range frames {
+		path, err := engine.SaveScreenshot(frame, ".")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved screenshot %s\n", path)
+	}
+	return nil
+}
+
+func initializeGame(wadPath string, pwadPaths []string, resolution engine.Resolution) error {
+	ebiten.SetWindowSize(windowWidth, int(math.Round(windowWidth/resolution.DisplayAspect())))
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetWindowTitle("Go Doom")
 
-	engine.LoadWadFile(wadPath)
-	startingMap := "E1M1"
+	var err error
+	wad, err = engine.OpenFiles(wadPath, pwadPaths...)
+	if err != nil {
+		return err
+	}
+	maps = engine.NewMapCache(wad, engine.DefaultMapCacheSize)
+
+	renderer, err = engine.NewRenderer(wad, resolution)
+	if err != nil {
+		return err
+	}
+	automap = engine.NewFramebuffer(resolution.Width, resolution.Height, renderer.Framebuffer.Palette)
+
+	mapNames = wad.ListMaps()
+	if len(mapNames) == 0 {
+		return fmt.Errorf("no maps found in `%s`", wadPath)
+	}
+	// start on the first map that loads, maps in unsupported formats are skipped
+	for index, name := range mapNames {
+		if err := selectMap(index); err != nil {
+			log.Printf("could not enter map %s: %v", name, err)
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("no map in `%s` could be loaded", wadPath)
+}
 
-	for level := 1; level <= 8; level++ {
-		levelName := fmt.Sprintf("E1M%d", level)
-		mapData[levelName] = engine.ReadMapData(levelName)
+// cycleResolution switches the renderer and the automap to the next of the preset resolutions, keeping the pixel
+// aspect.
+func cycleResolution() {
+	current := renderer.Resolution()
+	next := engine.Resolutions[0]
+	for i, resolution := range engine.Resolutions {
+		if resolution.Width == current.Width && resolution.Height == current.Height {
+			next = engine.Resolutions[(i+1)%len(engine.Resolutions)]
+		}
 	}
+	next.PixelAspect = current.PixelAspect
+	if err := renderer.SetResolution(next); err != nil {
+		log.Printf("could not switch to resolution %s: %v", next, err)
+		return
+	}
+	automap = engine.NewFramebuffer(next.Width, next.Height, renderer.Framebuffer.Palette)
+	log.Printf("resolution %s", next)
+}
 
-	currentMap = mapData[startingMap]
+// selectMap enters the map with the given index in mapNames, loading it if necessary. On error the current map is
+// kept.
+func selectMap(index int) error {
+	if index >= len(mapNames) {
+		return nil
+	}
+	levelData, err := maps.Get(mapNames[index])
+	if err != nil {
+		return err
+	}
+	currentMapIndex = index
+	currentMap = levelData
+	engine.SpawnPlayer(currentMap)
+	ebiten.SetWindowTitle(fmt.Sprintf("Go Doom - %s", mapNames[index]))
+	return nil
 }
 
 func (g *Game) Update() error {
+	tic++
+	renderer.Animate(tic)
+
 	sinA := math.Sin(engine.DegToRad(engine.PlayerAngle))
 	cosA := math.Cos(engine.DegToRad(engine.PlayerAngle))
 	speedSin := engine.PlayerMovementSpeed * sinA
@@ -50,62 +198,38 @@ func (g *Game) Update() error {
 	dx := 0.0
 	dy := 0.0
 
-	if ebiten.IsKeyPressed(ebiten.Key1) {
-		currentMap = mapData["E1M1"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key2) {
-		currentMap = mapData["E1M2"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key3) {
-		currentMap = mapData["E1M3"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key4) {
-		currentMap = mapData["E1M4"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key5) {
-		currentMap = mapData["E1M5"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key6) {
-		currentMap = mapData["E1M6"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key7) {
-		currentMap = mapData["E1M7"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
-	}
-	if ebiten.IsKeyPressed(ebiten.Key8) {
-		currentMap = mapData["E1M8"]
-		engine.PlayerOffsetX = 0
-		engine.PlayerOffsetY = 0
+	nextMapIndex := -1
+	for i, key := range mapSelectionKeys {
+		if inpututil.IsKeyJustPressed(key) {
+			nextMapIndex = i
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		nextMapIndex = (currentMapIndex + 1) % len(mapNames)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		nextMapIndex = (currentMapIndex + len(mapNames) - 1) % len(mapNames)
+	}
+	if nextMapIndex >= 0 {
+		if err := selectMap(nextMapIndex); err != nil {
+			log.Printf("could not enter map %s: %v", mapNames[nextMapIndex], err)
+		}
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		dx += -speedSin
-		dy += speedCos
-
+		dx += speedCos
+		dy += speedSin
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyS) {
-		dx += speedSin
-		dy += -speedCos
+		dx -= speedCos
+		dy -= speedSin
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyA) {
-		dx += -speedCos
-		dy += -speedSin
+		dx -= speedSin
+		dy += speedCos
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyD) {
-		dx += speedCos
-		dy += speedSin
+		dx += speedSin
+		dy -= speedCos
 	}
 
 	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
@@ -118,22 +242,74 @@ func (g *Game) Update() error {
 	if ebiten.IsKeyPressed(ebiten.KeyB) {
 		engine.DrawBoundingBoxesInMap = !engine.DrawBoundingBoxesInMap
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		showMap = !showMap
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		cycleResolution()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		screenshotRequested = true
+	}
 
-	// speed correction for both x and y movement
-	if dx != 0 && dy != 0 {
+	// speed correction for both forward and sideways movement
+	if (ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyS)) &&
+		(ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyD)) {
 		dx *= 1 / math.Sqrt(2)
 		dy *= 1 / math.Sqrt(2)
 	}
-	engine.PlayerOffsetX += float32(dx)
-	engine.PlayerOffsetY += float32(dy)
+	engine.PlayerX += dx
+	engine.PlayerY += dy
 
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	engine.DrawMap(screen, &currentMap)
+	frame := renderer.Framebuffer
+	if showMap {
+		engine.DrawMap(automap, currentMap)
+		frame = automap
+	} else {
+		renderer.RenderPlayerView(currentMap)
+	}
+	if screenshotRequested {
+		screenshotRequested = false
+		if path, err := engine.SaveScreenshot(frame, "."); err != nil {
+			log.Printf("could not save screenshot: %v", err)
+		} else {
+			log.Printf("saved screenshot %s", path)
+		}
+	}
+	drawFramebuffer(screen, frame)
+}
+
+// windowWidth is the initial width of the window, its height follows from the display aspect of the resolution
+const windowWidth = 1280
+
+var frameImage *ebiten.Image
+var framePixels []byte
+
+// drawFramebuffer shows the framebuffer on the screen, scaled to fit the window with the display aspect of the
+// resolution and centered.
+func drawFramebuffer(screen *ebiten.Image, frame *engine.Framebuffer) {
+	if frameImage == nil || frameImage.Bounds().Dx() != frame.Width || frameImage.Bounds().Dy() != frame.Height {
+		frameImage = ebiten.NewImage(frame.Width, frame.Height)
+		framePixels = make([]byte, 4*frame.Width*frame.Height)
+	}
+	frame.RGBA(framePixels)
+	frameImage.WritePixels(framePixels)
+
+	pixelAspect := renderer.Resolution().PixelAspect
+	screenWidth := float64(screen.Bounds().Dx())
+	screenHeight := float64(screen.Bounds().Dy())
+	scale := min(screenWidth/float64(frame.Width), screenHeight/(float64(frame.Height)*pixelAspect))
+	options := &ebiten.DrawImageOptions{}
+	options.GeoM.Scale(scale, scale*pixelAspect)
+	options.GeoM.Translate((screenWidth-scale*float64(frame.Width))/2,
+		(screenHeight-scale*pixelAspect*float64(frame.Height))/2)
+	screen.DrawImage(frameImage, options)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return engine.ScreenResX, engine.ScreenRexY
+	return outsideWidth, outsideHeight
 }